@@ -0,0 +1,349 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: asyncapi.go
+ * @time: 2026/10/19 01:30
+ */
+package gen
+
+import (
+	`encoding/json`
+	`fmt`
+	`strings`
+
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+const asyncApiVersion = "2.6.0"
+
+type asyncApi struct {
+	AsyncApi           string                      `json:"asyncapi"`
+	Info               asyncApiInfo                `json:"info"`
+	DefaultContentType string                      `json:"defaultContentType"`
+	Channels           map[string]*asyncApiChannel `json:"channels"`
+	Components         asyncApiComponents          `json:"components"`
+}
+
+type asyncApiInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type asyncApiChannel struct {
+	Description string             `json:"description,omitempty"`
+	Publish     *asyncApiOperation `json:"publish,omitempty"`   // 客户端 => 网关
+	Subscribe   *asyncApiOperation `json:"subscribe,omitempty"` // 网关 => 客户端
+}
+
+type asyncApiOperation struct {
+	OperationId string      `json:"operationId"`
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Message     interface{} `json:"message"`
+}
+
+type asyncApiMessage struct {
+	MessageId   string     `json:"messageId"`
+	Name        string     `json:"name"`
+	Title       string     `json:"title,omitempty"`
+	Summary     string     `json:"summary,omitempty"`
+	ContentType string     `json:"contentType"`
+	Headers     jsonSchema `json:"headers"`
+	Payload     jsonSchema `json:"payload"`
+}
+
+type asyncApiComponents struct {
+	Messages map[string]*asyncApiMessage `json:"messages"`
+	Schemas  map[string]jsonSchema       `json:"schemas"`
+}
+
+type jsonSchema map[string]interface{}
+
+func asyncApiRef(kind, name string) jsonSchema {
+	return jsonSchema{"$ref": "#/components/" + kind + "/" + name}
+}
+
+// 常用的google.protobuf类型在json中的表示
+var wellKnownSchemas = map[string]jsonSchema{
+	".google.protobuf.Timestamp":   {"type": "string", "format": "date-time"},
+	".google.protobuf.Duration":    {"type": "string"},
+	".google.protobuf.Empty":       {"type": "object"},
+	".google.protobuf.Any":         {"type": "object", "properties": jsonSchema{"@type": jsonSchema{"type": "string"}}},
+	".google.protobuf.Struct":      {"type": "object"},
+	".google.protobuf.Value":       {},
+	".google.protobuf.ListValue":   {"type": "array"},
+	".google.protobuf.FieldMask":   {"type": "string"},
+	".google.protobuf.StringValue": {"type": []string{"string", "null"}},
+	".google.protobuf.BytesValue":  {"type": []string{"string", "null"}, "format": "byte"},
+	".google.protobuf.BoolValue":   {"type": []string{"boolean", "null"}},
+	".google.protobuf.Int32Value":  {"type": []string{"integer", "null"}, "format": "int32"},
+	".google.protobuf.UInt32Value": {"type": []string{"integer", "null"}, "format": "uint32"},
+	".google.protobuf.FloatValue":  {"type": []string{"number", "null"}, "format": "float"},
+	".google.protobuf.DoubleValue": {"type": []string{"number", "null"}, "format": "double"},
+	".google.protobuf.Int64Value":  {"type": []string{"string", "integer", "null"}, "format": "int64"},
+	".google.protobuf.UInt64Value": {"type": []string{"string", "integer", "null"}, "format": "uint64"},
+}
+
+// 由注册表中的协议描述生成json schema, 与codec 1(json)的输出一致
+type asyncApiSchemas struct {
+	doc     *docTypes // 复用文档的注释查找
+	json    jsonOptions
+	schemas map[string]jsonSchema
+}
+
+// 返回协议的引用, 并生成其schema
+func (p *asyncApiSchemas) message(msg *descriptor.Message) jsonSchema {
+	name := msgFullName(msg)
+	if _, ok := p.schemas[name]; ok {
+		return asyncApiRef("schemas", name)
+	}
+	// 先登记再生成字段, 协议可能引用自身
+	schema := jsonSchema{"type": "object"}
+	p.schemas[name] = schema
+	path := p.doc.messagePath(msg)
+	if comment := p.doc.comment(msg.File, path); len(comment) > 0 {
+		schema["description"] = comment
+	}
+	props := jsonSchema{}
+	for i, f := range msg.Fields {
+		prop := p.field(f.FieldDescriptorProto)
+		if len(path) > 0 {
+			if comment := p.doc.comment(msg.File, fmt.Sprintf("%s,2,%d", path, i)); len(comment) > 0 {
+				if _, ok := prop["$ref"]; ok {
+					// $ref的同级属性会被忽略
+					prop = jsonSchema{"allOf": []jsonSchema{prop}}
+				}
+				prop["description"] = comment
+			}
+		}
+		name := f.GetJsonName()
+		if p.json.OrigName || len(name) < 1 {
+			name = f.GetName()
+		}
+		props[name] = prop
+	}
+	schema["properties"] = props
+	return asyncApiRef("schemas", name)
+}
+
+func (p *asyncApiSchemas) enum(typeName string) jsonSchema {
+	enum, err := p.doc.gen.reg.LookupEnum("", typeName)
+	if err != nil {
+		return jsonSchema{}
+	}
+	name := strings.TrimPrefix(typeName, ".")
+	if _, ok := p.schemas[name]; ok {
+		return asyncApiRef("schemas", name)
+	}
+	schema := jsonSchema{}
+	var values []interface{}
+	for _, v := range enum.GetValue() {
+		if p.json.EnumsAsInts {
+			values = append(values, v.GetNumber())
+		} else {
+			values = append(values, v.GetName())
+		}
+	}
+	if p.json.EnumsAsInts {
+		schema["type"] = "integer"
+	} else {
+		schema["type"] = "string"
+	}
+	schema["enum"] = values
+	if comment := p.doc.comment(enum.File, p.doc.enumPath(enum)); len(comment) > 0 {
+		schema["description"] = comment
+	}
+	p.schemas[name] = schema
+	return asyncApiRef("schemas", name)
+}
+
+func (p *asyncApiSchemas) field(f *descriptor2.FieldDescriptorProto) jsonSchema {
+	var schema jsonSchema
+	switch f.GetType() {
+	case descriptor2.FieldDescriptorProto_TYPE_DOUBLE:
+		schema = jsonSchema{"type": "number", "format": "double"}
+	case descriptor2.FieldDescriptorProto_TYPE_FLOAT:
+		schema = jsonSchema{"type": "number", "format": "float"}
+	case descriptor2.FieldDescriptorProto_TYPE_INT32, descriptor2.FieldDescriptorProto_TYPE_SINT32,
+		descriptor2.FieldDescriptorProto_TYPE_SFIXED32:
+		schema = jsonSchema{"type": "integer", "format": "int32"}
+	case descriptor2.FieldDescriptorProto_TYPE_UINT32, descriptor2.FieldDescriptorProto_TYPE_FIXED32:
+		schema = jsonSchema{"type": "integer", "format": "uint32"}
+	case descriptor2.FieldDescriptorProto_TYPE_INT64, descriptor2.FieldDescriptorProto_TYPE_SINT64,
+		descriptor2.FieldDescriptorProto_TYPE_SFIXED64:
+		// json中64位整数为字符串
+		schema = jsonSchema{"type": []string{"string", "integer"}, "format": "int64"}
+	case descriptor2.FieldDescriptorProto_TYPE_UINT64, descriptor2.FieldDescriptorProto_TYPE_FIXED64:
+		schema = jsonSchema{"type": []string{"string", "integer"}, "format": "uint64"}
+	case descriptor2.FieldDescriptorProto_TYPE_BOOL:
+		schema = jsonSchema{"type": "boolean"}
+	case descriptor2.FieldDescriptorProto_TYPE_STRING:
+		schema = jsonSchema{"type": "string"}
+	case descriptor2.FieldDescriptorProto_TYPE_BYTES:
+		schema = jsonSchema{"type": "string", "format": "byte"}
+	case descriptor2.FieldDescriptorProto_TYPE_ENUM:
+		schema = p.enum(f.GetTypeName())
+	case descriptor2.FieldDescriptorProto_TYPE_MESSAGE:
+		if wk, ok := wellKnownSchemas[f.GetTypeName()]; ok {
+			schema = jsonSchema{}
+			for k, v := range wk {
+				schema[k] = v
+			}
+			break
+		}
+		msg, err := p.doc.gen.reg.LookupMsg("", f.GetTypeName())
+		if err != nil {
+			schema = jsonSchema{}
+			break
+		}
+		if msg.GetOptions().GetMapEntry() && len(msg.Fields) == 2 {
+			return jsonSchema{"type": "object", "additionalProperties": p.field(msg.Fields[1].FieldDescriptorProto)}
+		}
+		schema = p.message(msg)
+	default:
+		schema = jsonSchema{}
+	}
+	if f.GetLabel() == descriptor2.FieldDescriptorProto_LABEL_REPEATED {
+		return jsonSchema{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// 包头, id固定为该协议的id
+func asyncApiHeaders(id uint16) jsonSchema {
+	return jsonSchema{
+		"type": "object",
+		"properties": jsonSchema{
+			"length": jsonSchema{"type": "integer", "description": "body长度, uint16小端"},
+			"seq":    jsonSchema{"type": "integer", "description": "序列号, 响应与请求相同, uint16小端"},
+			"id":     jsonSchema{"type": "integer", "const": id, "description": "协议id, uint16小端"},
+			"codec":  jsonSchema{"type": "integer", "description": "低8位 0:proto 1:json, 高8位 0x0100:gzip 0x0200:snappy, uint16小端"},
+		},
+	}
+}
+
+// 生成AsyncAPI 2.x文档: 每个@upid为publish, 每个@downid/@push/@declare为subscribe
+func (p *TcpGenerator) generateAsyncApi(file *descriptor.File) (string, error) {
+	routes, err := p.loadRoutes(file)
+	if err != nil {
+		return "", err
+	}
+	schemas := &asyncApiSchemas{
+		doc:     &docTypes{gen: p, msgSeen: map[string]bool{}, enumSeen: map[string]bool{}},
+		json:    p.jsonOpts,
+		schemas: map[string]jsonSchema{},
+	}
+	title := file.GetPackage()
+	if len(title) < 1 {
+		title = file.GetName()
+	}
+	out := &asyncApi{
+		AsyncApi: asyncApiVersion,
+		Info: asyncApiInfo{
+			Title:   title + " gateway",
+			Version: "1.0.0",
+			Description: "TCP/WebSocket网关协议, source: " + file.GetName() + "。\n" +
+				"每个数据包为8字节包头(Length, Seq, Id, Codec, 均为uint16小端)加消息体; " +
+				"消息体按Codec编码为protobuf或json, 下面的payload为json格式。",
+		},
+		DefaultContentType: "application/json",
+		Channels:           map[string]*asyncApiChannel{},
+		Components: asyncApiComponents{
+			Messages: map[string]*asyncApiMessage{},
+			Schemas:  schemas.schemas,
+		},
+	}
+
+	addMessage := func(name string, id uint16, msg *descriptor.Message, summary string) (jsonSchema, error) {
+		if _, ok := out.Components.Messages[name]; ok {
+			return nil, fmt.Errorf("asyncapi: message name %s is used more than once", name)
+		}
+		out.Components.Messages[name] = &asyncApiMessage{
+			MessageId:   name,
+			Name:        msgFullName(msg),
+			Title:       fmt.Sprintf("%d %s", id, msgFullName(msg)),
+			Summary:     summary,
+			ContentType: "application/json",
+			Headers:     asyncApiHeaders(id),
+			Payload:     schemas.message(msg),
+		}
+		return asyncApiRef("messages", name), nil
+	}
+
+	var errReply jsonSchema
+	if reply := routes.ErrorReply; reply != nil {
+		if errReply, err = addMessage(reply.GetName(), reply.Id, reply.Message, "错误响应"); err != nil {
+			return "", err
+		}
+	}
+	for _, m := range routes.Methods {
+		comment := docComment(m.Comment)
+		channel := &asyncApiChannel{Description: comment}
+		up, err := addMessage(m.GetName()+"Up", m.GetUpId(), m.Request, m.FullMethod+" 请求")
+		if err != nil {
+			return "", err
+		}
+		channel.Publish = &asyncApiOperation{
+			OperationId: lowerCamel(m.GetName()) + "Up",
+			Summary:     docStream(m.methodWithComment),
+			Message:     up,
+		}
+		if id := m.GetDownId(); id > 0 {
+			down, err := addMessage(m.GetName()+"Down", id, m.Response, m.FullMethod+" 响应")
+			if err != nil {
+				return "", err
+			}
+			var message interface{} = down
+			if errReply != nil {
+				message = jsonSchema{"oneOf": []jsonSchema{down, errReply}}
+			}
+			channel.Subscribe = &asyncApiOperation{
+				OperationId: lowerCamel(m.GetName()) + "Down",
+				Summary:     docStream(m.methodWithComment),
+				Message:     message,
+			}
+		}
+		out.Channels[m.Service.GetName()+"/"+m.GetName()] = channel
+	}
+	for _, d := range routes.Pushes {
+		msg, err := addMessage(d.GetName()+"Push", d.Id, d.Message, "服务端推送")
+		if err != nil {
+			return "", err
+		}
+		out.Channels["push/"+msgFullName(d.Message)] = &asyncApiChannel{
+			Subscribe: &asyncApiOperation{
+				OperationId: lowerCamel(d.GetName()) + "Push",
+				Summary:     "服务端推送",
+				Message:     msg,
+			},
+		}
+	}
+	for _, d := range routes.Declares {
+		if routes.ErrorReply != nil && d.Id == routes.ErrorReply.Id {
+			continue
+		}
+		msg, err := addMessage(d.GetName(), d.Id, d.Message, "声明的协议")
+		if err != nil {
+			return "", err
+		}
+		out.Channels["declare/"+msgFullName(d.Message)] = &asyncApiChannel{
+			Subscribe: &asyncApiOperation{
+				OperationId: lowerCamel(d.GetName()),
+				Summary:     "声明的协议",
+				Message:     msg,
+			},
+		}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
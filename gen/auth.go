@@ -0,0 +1,79 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: auth.go
+ * @time: 2026/10/18 20:45
+ */
+package gen
+
+import (
+	`fmt`
+	`strings`
+
+	`github.com/toolkits/slice`
+)
+
+// 方法的鉴权要求
+type authPolicy struct {
+	Level string // 生成代码中的常量名后缀: None/Required/Role
+	Role  string
+}
+
+// 解析 "none", "required", "role:<name>"
+func parseAuth(value string) (*authPolicy, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case len(value) < 1:
+		return nil, nil
+	case value == "none":
+		return &authPolicy{Level: "None"}, nil
+	case value == "required":
+		return &authPolicy{Level: "Required"}, nil
+	case strings.HasPrefix(value, "role:") && len(strings.TrimPrefix(value, "role:")) > 0:
+		return &authPolicy{Level: "Role", Role: strings.TrimPrefix(value, "role:")}, nil
+	}
+	return nil, fmt.Errorf("bad auth value %q, want none, required or role:<name>", value)
+}
+
+// 解析注释中的 @auth, 未设置返回nil; 只匹配完整的标签, 不会匹配 @author 等
+func parseAuthTag(lines []string) (*authPolicy, error) {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if !slice.ContainsString(fields, TagAuth) {
+			continue
+		}
+		if fields[0] != TagAuth || len(fields) < 2 {
+			return nil, fmt.Errorf("bad tag syntax %q, want '%s none|required|role:<name>'", line, TagAuth)
+		}
+		return parseAuth(fields[1])
+	}
+	return nil, nil
+}
+
+// 鉴权要求: 方法选项 > 方法注释 > 服务选项 > 服务注释 > 插件参数 auth, 默认none
+func (p *methodWithComment) GetAuth() *authPolicy {
+	if p.Route != nil {
+		if a, _ := parseAuth(p.Route.Auth); a != nil {
+			return a
+		}
+	}
+	if a, _ := parseAuthTag(p.CommentList); a != nil {
+		return a
+	}
+	if p.SvcRoute != nil {
+		if a, _ := parseAuth(p.SvcRoute.Auth); a != nil {
+			return a
+		}
+	}
+	if a, _ := parseAuthTag(p.SvcCommentList); a != nil {
+		return a
+	}
+	if a, _ := parseAuth(p.DefAuth); a != nil {
+		return a
+	}
+	return &authPolicy{Level: "None"}
+}
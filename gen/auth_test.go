@@ -0,0 +1,131 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: auth_test.go
+ * @time: 2026/10/19 11:25
+ */
+package gen
+
+import (
+	`testing`
+
+	`github.com/generalzgd/protoc-gen-grpc-tcpgw/options`
+)
+
+func TestParseAuth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *authPolicy
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "none", want: &authPolicy{Level: "None"}},
+		{value: "required", want: &authPolicy{Level: "Required"}},
+		{value: "role:admin", want: &authPolicy{Level: "Role", Role: "admin"}},
+		{value: " role:admin ", want: &authPolicy{Level: "Role", Role: "admin"}},
+		{value: "role:", wantErr: true},
+		{value: "role", wantErr: true},
+		{value: "admin", wantErr: true},
+		{value: "None", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAuth(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAuth(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseAuth(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseAuthTag(t *testing.T) {
+	tests := []struct {
+		lines   []string
+		want    *authPolicy
+		wantErr bool
+	}{
+		{lines: []string{"发送", "@upid 1"}, want: nil},
+		{lines: []string{"@author zgd"}, want: nil},
+		{lines: []string{"@auth required"}, want: &authPolicy{Level: "Required"}},
+		{lines: []string{"@auth role:op"}, want: &authPolicy{Level: "Role", Role: "op"}},
+		{lines: []string{"@auth"}, wantErr: true},
+		{lines: []string{"@auth role:"}, wantErr: true},
+		{lines: []string{"需要 @auth required"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAuthTag(tt.lines)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAuthTag(%q) error = %v, wantErr %v", tt.lines, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseAuthTag(%q) = %+v, want %+v", tt.lines, got, tt.want)
+		}
+	}
+}
+
+// 优先级: 方法选项 > 方法注释 > 服务选项 > 服务注释 > 插件参数
+func TestGetAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *methodWithComment
+		want authPolicy
+	}{
+		{
+			name: "default",
+			m:    &methodWithComment{},
+			want: authPolicy{Level: "None"},
+		},
+		{
+			name: "parameter",
+			m:    &methodWithComment{DefAuth: "required"},
+			want: authPolicy{Level: "Required"},
+		},
+		{
+			name: "service comment",
+			m:    &methodWithComment{SvcCommentList: []string{"@auth role:svc"}, DefAuth: "required"},
+			want: authPolicy{Level: "Role", Role: "svc"},
+		},
+		{
+			name: "service option",
+			m: &methodWithComment{
+				SvcRoute:       &options.ServiceRoute{Auth: "role:opt"},
+				SvcCommentList: []string{"@auth role:svc"},
+			},
+			want: authPolicy{Level: "Role", Role: "opt"},
+		},
+		{
+			name: "method comment",
+			m: &methodWithComment{
+				CommentList: []string{"@auth none"},
+				SvcRoute:    &options.ServiceRoute{Auth: "role:opt"},
+			},
+			want: authPolicy{Level: "None"},
+		},
+		{
+			name: "method option",
+			m: &methodWithComment{
+				Route:       &options.Route{Auth: "required"},
+				CommentList: []string{"@auth none"},
+			},
+			want: authPolicy{Level: "Required"},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.m.GetAuth(); *got != tt.want {
+			t.Errorf("%s: GetAuth() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
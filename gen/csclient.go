@@ -0,0 +1,398 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: csclient.go
+ * @time: 2026/10/18 23:20
+ */
+package gen
+
+import (
+	`bytes`
+	`fmt`
+	`strings`
+	`text/template`
+	`unicode`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+type csCmd struct {
+	Name    string // 枚举成员名
+	Id      uint16
+	Comment string
+}
+
+type csParser struct {
+	Id   uint16
+	Type string // C#类型全名, global::Namespace.Message
+}
+
+type csMethod struct {
+	Id   uint16
+	Name string // package.TargetService/Method
+}
+
+type csParam struct {
+	Source    string
+	Namespace string
+	Prefix    string
+	Cmds      []*csCmd
+	Parsers   []*csParser
+	Methods   []*csMethod
+	Pushes    []uint16
+	ErrorId   uint16 // error_reply的id, 0为未设置
+}
+
+// 与protoc的C#插件一致: 优先使用csharp_namespace, 否则为包名的PascalCase(保留'.')
+func csharpNamespace(file *descriptor.File) string {
+	if ns := file.GetOptions().GetCsharpNamespace(); len(ns) > 0 {
+		return ns
+	}
+	var out []rune
+	capNext := true
+	for _, c := range file.GetPackage() {
+		switch {
+		case c == '.':
+			out = append(out, c)
+			capNext = true
+		case unicode.IsLetter(c):
+			if capNext {
+				c = unicode.ToUpper(c)
+			}
+			out = append(out, c)
+			capNext = false
+		case unicode.IsDigit(c):
+			out = append(out, c)
+			capNext = true
+		default:
+			capNext = true
+		}
+	}
+	return string(out)
+}
+
+// Google.Protobuf生成的类名, 嵌套协议为 Outer.Types.Inner
+func csharpType(msg *descriptor.Message) string {
+	names := append(append([]string{}, msg.Outers...), msg.GetName())
+	name := strings.Join(names, ".Types.")
+	if ns := csharpNamespace(msg.File); len(ns) > 0 {
+		name = ns + "." + name
+	}
+	return "global::" + name
+}
+
+// 生成C#(Unity)的命令id枚举, 协议解析表及包头读写
+func (p *TcpGenerator) generateCs(file *descriptor.File) (string, error) {
+	routes, err := p.loadRoutes(file)
+	if err != nil {
+		return "", err
+	}
+
+	params := csParam{
+		Source:    file.GetName(),
+		Namespace: csharpNamespace(file),
+		Prefix:    p.DefinePrefix,
+	}
+	if len(params.Namespace) < 1 {
+		params.Namespace = "Tcpgw"
+	}
+	names := map[string]bool{"None": true}
+	addCmd := func(name string, id uint16, comment string) error {
+		if names[name] {
+			return fmt.Errorf("cs_client: command name %s is used more than once", name)
+		}
+		names[name] = true
+		params.Cmds = append(params.Cmds, &csCmd{Name: name, Id: id, Comment: comment})
+		return nil
+	}
+	for _, m := range routes.Methods {
+		if err := addCmd(m.GetName()+"Up", m.GetUpId(), m.FullMethod+" "+msgFullName(m.Request)); err != nil {
+			return "", err
+		}
+		if id := m.GetDownId(); id > 0 {
+			if err := addCmd(m.GetName()+"Down", id, m.FullMethod+" "+msgFullName(m.Response)); err != nil {
+				return "", err
+			}
+		}
+		params.Methods = append(params.Methods, &csMethod{Id: m.GetUpId(), Name: m.FullMethod})
+	}
+	for _, d := range routes.Pushes {
+		if err := addCmd(d.GetName()+"Push", d.Id, TagPush+" "+msgFullName(d.Message)); err != nil {
+			return "", err
+		}
+		params.Pushes = append(params.Pushes, d.Id)
+	}
+	for _, d := range routes.Declares {
+		if err := addCmd(d.GetName(), d.Id, TagDeclare+" "+msgFullName(d.Message)); err != nil {
+			return "", err
+		}
+	}
+	for _, it := range routes.Ids() {
+		params.Parsers = append(params.Parsers, &csParser{Id: it.Id, Type: csharpType(it.Message)})
+	}
+	if routes.ErrorReply != nil {
+		params.ErrorId = routes.ErrorReply.Id
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := csTemplate.Execute(out, params); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+var csTemplate = template.Must(template.New("cs").Parse(`// <auto-generated>
+//   Code generated by protoc-gen-grpc-tcpgw. DO NOT EDIT.
+//   source: {{.Source}}
+// </auto-generated>
+using System;
+using System.Collections.Generic;
+using System.IO;
+using System.IO.Compression;
+using Google.Protobuf;
+
+namespace {{.Namespace}} {
+{{- $prefix := .Prefix}}
+
+  // 命令id: 方法的@upid/@downid, 推送(@push)及声明(@declare)协议的id
+  public enum {{$prefix}}CmdId : ushort {
+    None = 0,
+{{- range .Cmds}}
+    // {{.Comment}}
+    {{.Name}} = {{.Id}},
+{{- end}}
+  }
+
+  // codec: 低8位为编码格式, 高8位为压缩标记
+  public static class {{$prefix}}Codec {
+    public const ushort Proto = 0;
+    public const ushort Json = 1;
+    public const ushort FormatMask = 0x00ff;
+    public const ushort FlagGzip = 0x0100;
+    public const ushort FlagSnappy = 0x0200;
+  }
+
+  // 网关错误码, 与生成的Go代码一致; 后端错误为grpc状态码(0~16)
+  public static class {{$prefix}}ErrCode {
+    public const uint Args = 1000;
+    public const uint Codec = 1001;
+    public const uint Method = 1002;
+    public const uint Dial = 1003;
+    public const uint QueueFull = 1004;
+    public const uint Closed = 1005;
+    public const uint Stream = 1006;
+    public const uint RateLimit = 1007;
+    public const uint Auth = 1008;
+  }
+
+  // 包头, 各字段均为uint16小端
+  public struct {{$prefix}}PackHead {
+    public const int Size = 8;
+
+    public ushort Length; // body的长度
+    public ushort Seq;    // 序列号
+    public ushort Id;     // 协议id
+    public ushort Codec;  // 低8位编码格式, 高8位压缩标记
+
+    public static {{$prefix}}PackHead Read(byte[] buf, int offset) {
+      if (buf.Length - offset < Size) {
+        throw new ArgumentException("pack head too short");
+      }
+      return new {{$prefix}}PackHead {
+        Length = ReadUInt16(buf, offset),
+        Seq = ReadUInt16(buf, offset + 2),
+        Id = ReadUInt16(buf, offset + 4),
+        Codec = ReadUInt16(buf, offset + 6),
+      };
+    }
+
+    public void Write(byte[] buf, int offset) {
+      if (buf.Length - offset < Size) {
+        throw new ArgumentException("pack head too short");
+      }
+      WriteUInt16(buf, offset, Length);
+      WriteUInt16(buf, offset + 2, Seq);
+      WriteUInt16(buf, offset + 4, Id);
+      WriteUInt16(buf, offset + 6, Codec);
+    }
+
+    static ushort ReadUInt16(byte[] buf, int offset) {
+      return (ushort)(buf[offset] | (buf[offset + 1] << 8));
+    }
+
+    static void WriteUInt16(byte[] buf, int offset, ushort v) {
+      buf[offset] = (byte)v;
+      buf[offset + 1] = (byte)(v >> 8);
+    }
+  }
+
+  // 网关包: 包头+消息体
+  public sealed class {{$prefix}}Pack {
+    public {{$prefix}}PackHead Head;
+    public byte[] Body;
+
+    // 编码为完整的数据包, codec为Proto或Json(不压缩)
+    public static byte[] Encode(ushort seq, ushort id, IMessage msg, ushort codec = {{$prefix}}Codec.Proto) {
+      byte[] body;
+      switch (codec & {{$prefix}}Codec.FormatMask) {
+        case {{$prefix}}Codec.Proto:
+          body = msg.ToByteArray();
+          break;
+        case {{$prefix}}Codec.Json:
+          body = System.Text.Encoding.UTF8.GetBytes(JsonFormatter.Default.Format(msg));
+          break;
+        default:
+          throw new ArgumentException("unknown codec " + codec);
+      }
+      if (body.Length > ushort.MaxValue) {
+        throw new ArgumentException("body too large: " + body.Length);
+      }
+      var buf = new byte[{{$prefix}}PackHead.Size + body.Length];
+      var head = new {{$prefix}}PackHead {
+        Length = (ushort)body.Length,
+        Seq = seq,
+        Id = id,
+        Codec = (ushort)(codec & {{$prefix}}Codec.FormatMask),
+      };
+      head.Write(buf, 0);
+      Buffer.BlockCopy(body, 0, buf, {{$prefix}}PackHead.Size, body.Length);
+      return buf;
+    }
+
+    // 按id解析消息体, 未注册的id返回null; 支持gzip压缩, snappy需自行解压后调用
+    public IMessage Decode() {
+      var parser = {{$prefix}}MessageRegistry.GetParser(Head.Id);
+      if (parser == null) {
+        return null;
+      }
+      var body = Body;
+      if ((Head.Codec & {{$prefix}}Codec.FlagGzip) != 0) {
+        body = Gunzip(body);
+      } else if ((Head.Codec & {{$prefix}}Codec.FlagSnappy) != 0) {
+        throw new NotSupportedException("snappy body is not supported");
+      }
+      switch (Head.Codec & {{$prefix}}Codec.FormatMask) {
+        case {{$prefix}}Codec.Proto:
+          return parser.ParseFrom(body);
+        case {{$prefix}}Codec.Json:
+          return parser.ParseJson(System.Text.Encoding.UTF8.GetString(body));
+        default:
+          throw new NotSupportedException("unknown codec " + Head.Codec);
+      }
+    }
+
+    static byte[] Gunzip(byte[] data) {
+      using (var input = new GZipStream(new MemoryStream(data), CompressionMode.Decompress))
+      using (var output = new MemoryStream()) {
+        input.CopyTo(output);
+        return output.ToArray();
+      }
+    }
+  }
+
+  // 从tcp流中切分数据包
+  public sealed class {{$prefix}}PackReader {
+    byte[] buf = new byte[4096];
+    int count;
+
+    public void Feed(byte[] data, int offset, int length) {
+      if (count + length > buf.Length) {
+        var next = new byte[Math.Max(buf.Length * 2, count + length)];
+        Buffer.BlockCopy(buf, 0, next, 0, count);
+        buf = next;
+      }
+      Buffer.BlockCopy(data, offset, buf, count, length);
+      count += length;
+    }
+
+    // 读取一个完整的数据包, 数据不足时返回false
+    public bool TryRead(out {{$prefix}}Pack pack) {
+      pack = null;
+      if (count < {{$prefix}}PackHead.Size) {
+        return false;
+      }
+      var head = {{$prefix}}PackHead.Read(buf, 0);
+      var size = {{$prefix}}PackHead.Size + head.Length;
+      if (count < size) {
+        return false;
+      }
+      var body = new byte[head.Length];
+      Buffer.BlockCopy(buf, {{$prefix}}PackHead.Size, body, 0, head.Length);
+      Buffer.BlockCopy(buf, size, buf, 0, count - size);
+      count -= size;
+      pack = new {{$prefix}}Pack { Head = head, Body = body };
+      return true;
+    }
+  }
+
+  // id => 协议解析器, 包括请求/响应/推送/声明的协议
+  public static class {{$prefix}}MessageRegistry {
+    static readonly Dictionary<ushort, MessageParser> parsers = new Dictionary<ushort, MessageParser> {
+{{- range .Parsers}}
+      { {{.Id}}, {{.Type}}.Parser },
+{{- end}}
+    };
+
+    // @upid => package.TargetService/Method
+    static readonly Dictionary<ushort, string> id2meth = new Dictionary<ushort, string> {
+{{- range .Methods}}
+      { {{.Id}}, "{{.Name}}" },
+{{- end}}
+    };
+
+    static readonly HashSet<ushort> pushIds = new HashSet<ushort> {
+{{- range .Pushes}}
+      {{.}},
+{{- end}}
+    };
+
+    // error_reply的id, 0为未设置
+    public const ushort ErrorReplyId = {{.ErrorId}};
+
+    public static MessageParser GetParser(ushort id) {
+      MessageParser parser;
+      return parsers.TryGetValue(id, out parser) ? parser : null;
+    }
+
+    public static string GetMethodById(ushort id) {
+      string meth;
+      return id2meth.TryGetValue(id, out meth) ? meth : "";
+    }
+
+    public static bool IsPush(ushort id) {
+      return pushIds.Contains(id);
+    }
+  }
+
+  // 按id分发收到的数据包, 应在主线程调用Dispatch
+  public sealed class {{$prefix}}Dispatcher {
+    readonly Dictionary<ushort, Action<{{$prefix}}Pack, IMessage>> handlers = new Dictionary<ushort, Action<{{$prefix}}Pack, IMessage>>();
+
+    public void On<T>({{$prefix}}CmdId id, Action<{{$prefix}}Pack, T> handler) where T : IMessage {
+      handlers[(ushort)id] = (pack, msg) => handler(pack, (T)msg);
+    }
+
+    public void Off({{$prefix}}CmdId id) {
+      handlers.Remove((ushort)id);
+    }
+
+    // 没有对应的处理函数或未注册的id返回false
+    public bool Dispatch({{$prefix}}Pack pack) {
+      Action<{{$prefix}}Pack, IMessage> handler;
+      if (!handlers.TryGetValue(pack.Head.Id, out handler)) {
+        return false;
+      }
+      var msg = pack.Decode();
+      if (msg == null) {
+        return false;
+      }
+      handler(pack, msg);
+      return true;
+    }
+  }
+}
+`))
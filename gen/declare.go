@@ -0,0 +1,96 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: declare.go
+ * @time: 2026/10/18 15:02
+ */
+package gen
+
+import (
+	`errors`
+	`fmt`
+	`strconv`
+	`strings`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 声明的错误/通知协议, 注册到id2struct/structName2id
+type declaredMessage struct {
+	*descriptor.Message
+	Id      uint16
+	Pos     string           // 声明的位置, 服务注释或插件参数
+	OutFile *descriptor.File // 生成代码所在的文件
+}
+
+// 生成代码中的类型名, 与生成文件同一个go包时不加包名(对应generate中跳过的import)
+func (p *declaredMessage) GetGoType() string {
+	if p.OutFile != nil && p.File.GoPkg == p.OutFile.GoPkg {
+		return p.GetName()
+	}
+	pkg := p.File.GetPackage()
+	if len(pkg) > 0 {
+		return pkg + "." + p.GetName()
+	}
+	return p.GetName()
+}
+
+// 解析 "package.Message id" 或 "package.Message=id"
+func parseDeclare(spec string) (string, uint16, error) {
+	spec = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(spec), TagDeclare))
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '='
+	})
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("bad declare %q, want 'package.Message id'", spec)
+	}
+	v, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("bad declare id %q", fields[1])
+	}
+	if v < 1 || v > maxRouteId {
+		return "", 0, fmt.Errorf("declare id %d out of range [1, %d]", v, maxRouteId)
+	}
+	return fields[0], uint16(v), nil
+}
+
+// 收集文件中声明的协议: 插件参数 declare 及服务注释 @declare
+func (p *TcpGenerator) loadDeclares(file *descriptor.File) ([]*declaredMessage, error) {
+	var list []*declaredMessage
+	var errs []string
+	add := func(spec, pos string) {
+		name, id, err := parseDeclare(spec)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", pos, err))
+			return
+		}
+		msg, err := p.reg.LookupMsg(file.GetPackage(), name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", pos, err))
+			return
+		}
+		list = append(list, &declaredMessage{Message: msg, Id: id, Pos: pos, OutFile: file})
+	}
+
+	for _, spec := range p.declares {
+		add(spec, "parameter declare="+spec)
+	}
+	comments := p.reg.fileComments[file.GetName()]
+	for si := range file.Services {
+		path := fmt.Sprintf("%d,%d", servicePath, si)
+		for _, line := range strings.Split(comments[path], "\n") {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+			if strings.HasPrefix(line, TagDeclare) {
+				add(line, p.reg.SourcePos(file.GetName(), path))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return list, nil
+}
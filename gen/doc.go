@@ -0,0 +1,583 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: doc.go
+ * @time: 2026/10/19 00:40
+ */
+package gen
+
+import (
+	`bytes`
+	`fmt`
+	htmltemplate `html/template`
+	`strconv`
+	`strings`
+	`text/template`
+
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+type docField struct {
+	Name    string
+	Label   string // repeated, 其他为空
+	Type    string
+	Link    string // 类型的锚点, 只有文档中列出的协议/枚举才有
+	Comment string
+}
+
+type docMessage struct {
+	FullName string
+	Anchor   string
+	Comment  string
+	Fields   []*docField
+}
+
+type docEnumValue struct {
+	Name    string
+	Number  int32
+	Comment string
+}
+
+type docEnum struct {
+	FullName string
+	Anchor   string
+	Comment  string
+	Values   []*docEnumValue
+}
+
+type docRoute struct {
+	*routeMethod
+	Gateway   string // 网关proto中的 Service/Method
+	Target    string // 后端 package.Service/Method
+	Stream    string
+	Auth      string
+	Timeout   string
+	Compress  string
+	RateLimit string
+	Comment   string
+	Req       *docMessage
+	Resp      *docMessage
+}
+
+type docId struct {
+	Id      uint16
+	Message *docMessage
+}
+
+type docParam struct {
+	Source     string
+	Package    string
+	Routes     []*docRoute
+	Pushes     []*docId
+	Declares   []*docId
+	ErrorReply uint16
+	Messages   []*docMessage // 字段引用的协议
+	Enums      []*docEnum
+}
+
+// 文档中引用的协议及枚举, 按发现的顺序输出
+type docTypes struct {
+	gen      *TcpGenerator
+	msgSeen  map[string]bool
+	enumSeen map[string]bool
+	messages []*descriptor.Message
+	enums    []*descriptor.Enum
+}
+
+func docAnchor(kind, fqmn string) string {
+	return kind + "-" + strings.ToLower(strings.Replace(strings.TrimPrefix(fqmn, "."), ".", "-", -1))
+}
+
+// 去掉注释中的标签行, 标签已在文档中单独列出
+func docComment(comment string) string {
+	var li []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		if len(line) < 1 || strings.HasPrefix(line, "@") {
+			continue
+		}
+		li = append(li, line)
+	}
+	return strings.Join(li, "\n")
+}
+
+// SourceCodeInfo中协议的路径, 嵌套协议为 4,i,3,j
+func (p *docTypes) messagePath(msg *descriptor.Message) string {
+	var li []string
+	outer := ""
+	if pkg := msg.File.GetPackage(); len(pkg) > 0 {
+		outer = "." + pkg
+	}
+	for i, name := range msg.Outers {
+		outer += "." + name
+		tag := messagePath
+		if i > 0 {
+			tag = 3 // nested_type
+		}
+		o, err := p.gen.reg.LookupMsg("", outer)
+		if err != nil {
+			return ""
+		}
+		li = append(li, strconv.Itoa(tag), strconv.Itoa(o.Index))
+	}
+	tag := messagePath
+	if len(msg.Outers) > 0 {
+		tag = 3
+	}
+	li = append(li, strconv.Itoa(tag), strconv.Itoa(msg.Index))
+	return strings.Join(li, ",")
+}
+
+func (p *docTypes) enumPath(enum *descriptor.Enum) string {
+	if len(enum.Outers) < 1 {
+		return fmt.Sprintf("5,%d", enum.Index)
+	}
+	name := strings.Join(enum.Outers, ".")
+	if pkg := enum.File.GetPackage(); len(pkg) > 0 {
+		name = pkg + "." + name
+	}
+	outer, err := p.gen.reg.LookupMsg("", "."+name)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s,4,%d", p.messagePath(outer), enum.Index)
+}
+
+func (p *docTypes) comment(file *descriptor.File, path string) string {
+	if len(path) < 1 {
+		return ""
+	}
+	return docComment(p.gen.reg.fileComments[file.GetName()][path])
+}
+
+func (p *docTypes) addMessage(name string) string {
+	if strings.HasPrefix(name, ".google.protobuf.") {
+		return ""
+	}
+	msg, err := p.gen.reg.LookupMsg("", name)
+	if err != nil {
+		return ""
+	}
+	if !p.msgSeen[name] {
+		p.msgSeen[name] = true
+		p.messages = append(p.messages, msg)
+	}
+	return docAnchor("msg", name)
+}
+
+func (p *docTypes) addEnum(name string) string {
+	enum, err := p.gen.reg.LookupEnum("", name)
+	if err != nil {
+		return ""
+	}
+	if !p.enumSeen[name] {
+		p.enumSeen[name] = true
+		p.enums = append(p.enums, enum)
+	}
+	return docAnchor("enum", name)
+}
+
+// 字段类型, 引用的协议/枚举加入文档
+func (p *docTypes) fieldType(f *descriptor2.FieldDescriptorProto) (string, string) {
+	switch f.GetType() {
+	case descriptor2.FieldDescriptorProto_TYPE_MESSAGE:
+		if entry, err := p.gen.reg.LookupMsg("", f.GetTypeName()); err == nil && entry.GetOptions().GetMapEntry() && len(entry.Fields) == 2 {
+			key, _ := p.fieldType(entry.Fields[0].FieldDescriptorProto)
+			value, link := p.fieldType(entry.Fields[1].FieldDescriptorProto)
+			return fmt.Sprintf("map<%s, %s>", key, value), link
+		}
+		return strings.TrimPrefix(f.GetTypeName(), "."), p.addMessage(f.GetTypeName())
+	case descriptor2.FieldDescriptorProto_TYPE_ENUM:
+		return strings.TrimPrefix(f.GetTypeName(), "."), p.addEnum(f.GetTypeName())
+	}
+	return strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_")), ""
+}
+
+func (p *docTypes) message(msg *descriptor.Message) *docMessage {
+	path := p.messagePath(msg)
+	it := &docMessage{
+		FullName: msgFullName(msg),
+		Anchor:   docAnchor("msg", msg.FQMN()),
+		Comment:  p.comment(msg.File, path),
+	}
+	for i, f := range msg.Fields {
+		field := &docField{Name: f.GetName()}
+		if f.GetLabel() == descriptor2.FieldDescriptorProto_LABEL_REPEATED {
+			field.Label = "repeated"
+		}
+		field.Type, field.Link = p.fieldType(f.FieldDescriptorProto)
+		if strings.HasPrefix(field.Type, "map<") {
+			field.Label = ""
+		}
+		if len(path) > 0 {
+			field.Comment = p.comment(msg.File, fmt.Sprintf("%s,2,%d", path, i))
+		}
+		it.Fields = append(it.Fields, field)
+	}
+	return it
+}
+
+func (p *docTypes) enum(enum *descriptor.Enum) *docEnum {
+	name := enum.GetName()
+	if len(enum.Outers) > 0 {
+		name = strings.Join(enum.Outers, ".") + "." + name
+	}
+	if pkg := enum.File.GetPackage(); len(pkg) > 0 {
+		name = pkg + "." + name
+	}
+	path := p.enumPath(enum)
+	it := &docEnum{
+		FullName: name,
+		Anchor:   docAnchor("enum", name),
+		Comment:  p.comment(enum.File, path),
+	}
+	for i, v := range enum.GetValue() {
+		value := &docEnumValue{Name: v.GetName(), Number: v.GetNumber()}
+		if len(path) > 0 {
+			value.Comment = p.comment(enum.File, fmt.Sprintf("%s,2,%d", path, i))
+		}
+		it.Values = append(it.Values, value)
+	}
+	return it
+}
+
+func docStream(m *methodWithComment) string {
+	switch {
+	case m.IsBidiStream():
+		return "双向流"
+	case m.IsServerStream():
+		return "服务端流"
+	case m.IsClientStream():
+		return "客户端流"
+	}
+	return "单次调用"
+}
+
+// 与生成代码一致: 流式方法只使用方法级超时
+func docTimeout(m *methodWithComment) string {
+	d := m.GetTimeout()
+	if m.GetServerStreaming() || m.GetClientStreaming() {
+		d = m.getMethodTimeout()
+	}
+	if d <= 0 {
+		return "-"
+	}
+	return d.String()
+}
+
+func docAuth(m *methodWithComment) string {
+	auth := m.GetAuth()
+	if auth.Level == "Role" {
+		return "role:" + auth.Role
+	}
+	return strings.ToLower(auth.Level)
+}
+
+func (p *TcpGenerator) loadDoc(file *descriptor.File) (*docParam, error) {
+	routes, err := p.loadRoutes(file)
+	if err != nil {
+		return nil, err
+	}
+	types := &docTypes{gen: p, msgSeen: map[string]bool{}, enumSeen: map[string]bool{}}
+	params := &docParam{Source: file.GetName(), Package: file.GetPackage()}
+	for _, m := range routes.Methods {
+		it := &docRoute{
+			routeMethod: m,
+			Gateway:     m.Service.GetName() + "/" + m.GetName(),
+			Target:      m.GetTargetSvrPackage() + m.GetTargetSvrName() + "/" + m.GetName(),
+			Stream:      docStream(m.methodWithComment),
+			Auth:        docAuth(m.methodWithComment),
+			Timeout:     docTimeout(m.methodWithComment),
+			Comment:     docComment(m.Comment),
+			Req:         types.message(m.Request),
+			Resp:        types.message(m.Response),
+		}
+		if c := m.GetCompress(); c != nil {
+			it.Compress = fmt.Sprintf("> %d bytes, %s", c.Threshold, strings.ToLower(strings.TrimPrefix(c.Flag, "CodecFlag")))
+		}
+		if r := m.GetRateLimit(); r != nil {
+			it.RateLimit = fmt.Sprintf("%s/s burst=%d", r.RateExpr(), r.Burst)
+			if len(r.By) > 0 {
+				it.RateLimit += " by=" + r.By
+			}
+		}
+		params.Routes = append(params.Routes, it)
+	}
+	for _, d := range routes.Pushes {
+		params.Pushes = append(params.Pushes, &docId{Id: d.Id, Message: types.message(d.Message)})
+	}
+	for _, d := range routes.Declares {
+		params.Declares = append(params.Declares, &docId{Id: d.Id, Message: types.message(d.Message)})
+	}
+	if routes.ErrorReply != nil {
+		params.ErrorReply = routes.ErrorReply.Id
+	}
+	// 字段引用的协议可能继续引用其他协议
+	for i := 0; i < len(types.messages); i++ {
+		params.Messages = append(params.Messages, types.message(types.messages[i]))
+	}
+	for _, enum := range types.enums {
+		params.Enums = append(params.Enums, types.enum(enum))
+	}
+	return params, nil
+}
+
+// 生成Markdown及HTML格式的协议文档
+func (p *TcpGenerator) generateDoc(file *descriptor.File) (string, string, error) {
+	params, err := p.loadDoc(file)
+	if err != nil {
+		return "", "", err
+	}
+	md := bytes.NewBuffer(nil)
+	if err := mdDocTemplate.Execute(md, params); err != nil {
+		return "", "", err
+	}
+	html := bytes.NewBuffer(nil)
+	if err := htmlDocTemplate.Execute(html, params); err != nil {
+		return "", "", err
+	}
+	return strings.TrimRight(md.String(), "\n") + "\n", html.String(), nil
+}
+
+// markdown表格中的单元格
+func mdCell(s string) string {
+	if len(s) < 1 {
+		return "-"
+	}
+	r := strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;", "\n", "<br>")
+	return r.Replace(s)
+}
+
+// html中保留换行
+func htmlLines(s string) htmltemplate.HTML {
+	return htmltemplate.HTML(strings.Replace(htmltemplate.HTMLEscapeString(s), "\n", "<br>", -1))
+}
+
+var mdDocTemplate = template.Must(template.New("md").Funcs(template.FuncMap{"cell": mdCell}).Parse(`<!-- Code generated by protoc-gen-grpc-tcpgw. DO NOT EDIT. -->
+<!-- source: {{.Source}} -->
+# {{.Source}} 网关协议
+
+包头为4个uint16(小端): Length(body长度), Seq(序列号, 响应与请求相同), Id(协议id), Codec(低8位 0:proto 1:json, 高8位 0x0100:gzip 0x0200:snappy)。
+{{- if .ErrorReply}}
+
+调用失败时返回错误响应协议, id为 {{.ErrorReply}}。
+{{- end}}
+
+## 路由
+
+| 上行id | 下行id | 方法 | 类型 | 请求 | 响应 | 鉴权 | 超时 |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+{{- range .Routes}}
+| {{.GetUpId}} | {{if .GetDownId}}{{.GetDownId}}{{else}}-{{end}} | [{{.Gateway}}](#route-{{.GetUpId}}) | {{.Stream}} | {{.Req.FullName}} | {{.Resp.FullName}} | {{.Auth}} | {{.Timeout}} |
+{{- end}}
+{{range .Routes}}
+<a id="route-{{.GetUpId}}"></a>
+### {{.Gateway}}
+{{if .Comment}}
+{{.Comment}}
+{{end}}
+| 项 | 值 |
+| --- | --- |
+| 上行(客户端 → 网关) | {{.GetUpId}}, {{.Req.FullName}} |
+| 下行(网关 → 客户端) | {{if .GetDownId}}{{.GetDownId}}{{else}}-{{end}}, {{.Resp.FullName}} |
+| 类型 | {{.Stream}} |
+| 后端 | {{.Target}} |
+| 网关方法 | {{.FullMethod}} |
+| 鉴权 | {{.Auth}} |
+| 超时 | {{.Timeout}} |
+{{- if .Compress}}
+| 压缩 | {{cell .Compress}} |
+{{- end}}
+{{- if .RateLimit}}
+| 限流 | {{.RateLimit}} |
+{{- end}}
+
+请求 {{.Req.FullName}}
+
+{{template "fields" .Req}}
+响应 {{.Resp.FullName}}
+
+{{template "fields" .Resp}}
+{{- end}}
+{{- if .Pushes}}
+## 推送
+{{range .Pushes}}
+### {{.Id}} {{.Message.FullName}}
+{{template "message" .Message}}
+{{- end}}
+{{- end}}
+{{- if .Declares}}
+## 声明的协议
+{{range .Declares}}
+### {{.Id}} {{.Message.FullName}}
+{{template "message" .Message}}
+{{- end}}
+{{- end}}
+{{- if .Messages}}
+## 引用的协议
+{{range .Messages}}
+<a id="{{.Anchor}}"></a>
+### {{.FullName}}
+{{template "message" .}}
+{{- end}}
+{{- end}}
+{{- if .Enums}}
+## 枚举
+{{range .Enums}}
+<a id="{{.Anchor}}"></a>
+### {{.FullName}}
+{{if .Comment}}
+{{.Comment}}
+{{end}}
+| 名称 | 值 | 说明 |
+| --- | --- | --- |
+{{- range .Values}}
+| {{.Name}} | {{.Number}} | {{cell .Comment}} |
+{{- end}}
+{{end}}
+{{- end}}
+{{- define "message"}}
+{{- if .Comment}}
+{{.Comment}}
+{{end}}
+{{template "fields" .}}
+{{- end}}
+{{- define "fields"}}
+{{- if .Fields -}}
+| 字段 | 类型 | 说明 |
+| --- | --- | --- |
+{{- range .Fields}}
+| {{.Name}} | {{if .Label}}{{.Label}} {{end}}{{if .Link}}[{{cell .Type}}](#{{.Link}}){{else}}{{cell .Type}}{{end}} | {{cell .Comment}} |
+{{- end}}
+{{- else -}}
+无字段
+{{- end}}
+{{end}}
+`))
+
+var htmlDocTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{"lines": htmlLines}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="generator" content="protoc-gen-grpc-tcpgw">
+<title>{{.Source}} 网关协议</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 2em auto; max-width: 1100px; color: #24292e; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #dfe2e5; padding: 4px 10px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code { font-family: Menlo, Consolas, monospace; }
+h3 { margin-top: 2em; }
+.comment { color: #586069; }
+</style>
+</head>
+<body>
+<h1>{{.Source}} 网关协议</h1>
+<p>包头为4个uint16(小端): Length(body长度), Seq(序列号, 响应与请求相同), Id(协议id), Codec(低8位 0:proto 1:json, 高8位 0x0100:gzip 0x0200:snappy)。</p>
+{{- if .ErrorReply}}
+<p>调用失败时返回错误响应协议, id为 {{.ErrorReply}}。</p>
+{{- end}}
+
+<h2>路由</h2>
+<table>
+<tr><th>上行id</th><th>下行id</th><th>方法</th><th>类型</th><th>请求</th><th>响应</th><th>鉴权</th><th>超时</th></tr>
+{{- range .Routes}}
+<tr><td>{{.GetUpId}}</td><td>{{if .GetDownId}}{{.GetDownId}}{{else}}-{{end}}</td><td><a href="#route-{{.GetUpId}}">{{.Gateway}}</a></td><td>{{.Stream}}</td><td><code>{{.Req.FullName}}</code></td><td><code>{{.Resp.FullName}}</code></td><td>{{.Auth}}</td><td>{{.Timeout}}</td></tr>
+{{- end}}
+</table>
+{{range .Routes}}
+<h3 id="route-{{.GetUpId}}">{{.Gateway}}</h3>
+{{- if .Comment}}
+<p class="comment">{{lines .Comment}}</p>
+{{- end}}
+<table>
+<tr><th>上行(客户端 → 网关)</th><td>{{.GetUpId}}, <code>{{.Req.FullName}}</code></td></tr>
+<tr><th>下行(网关 → 客户端)</th><td>{{if .GetDownId}}{{.GetDownId}}{{else}}-{{end}}, <code>{{.Resp.FullName}}</code></td></tr>
+<tr><th>类型</th><td>{{.Stream}}</td></tr>
+<tr><th>后端</th><td><code>{{.Target}}</code></td></tr>
+<tr><th>网关方法</th><td><code>{{.FullMethod}}</code></td></tr>
+<tr><th>鉴权</th><td>{{.Auth}}</td></tr>
+<tr><th>超时</th><td>{{.Timeout}}</td></tr>
+{{- if .Compress}}
+<tr><th>压缩</th><td>{{.Compress}}</td></tr>
+{{- end}}
+{{- if .RateLimit}}
+<tr><th>限流</th><td>{{.RateLimit}}</td></tr>
+{{- end}}
+</table>
+<p>请求 <code>{{.Req.FullName}}</code></p>
+{{template "fields" .Req}}
+<p>响应 <code>{{.Resp.FullName}}</code></p>
+{{template "fields" .Resp}}
+{{- end}}
+{{- if .Pushes}}
+
+<h2>推送</h2>
+{{- range .Pushes}}
+<h3>{{.Id}} {{.Message.FullName}}</h3>
+{{- if .Message.Comment}}
+<p class="comment">{{lines .Message.Comment}}</p>
+{{- end}}
+{{template "fields" .Message}}
+{{- end}}
+{{- end}}
+{{- if .Declares}}
+
+<h2>声明的协议</h2>
+{{- range .Declares}}
+<h3>{{.Id}} {{.Message.FullName}}</h3>
+{{- if .Message.Comment}}
+<p class="comment">{{lines .Message.Comment}}</p>
+{{- end}}
+{{template "fields" .Message}}
+{{- end}}
+{{- end}}
+{{- if .Messages}}
+
+<h2>引用的协议</h2>
+{{- range .Messages}}
+<h3 id="{{.Anchor}}">{{.FullName}}</h3>
+{{- if .Comment}}
+<p class="comment">{{lines .Comment}}</p>
+{{- end}}
+{{template "fields" .}}
+{{- end}}
+{{- end}}
+{{- if .Enums}}
+
+<h2>枚举</h2>
+{{- range .Enums}}
+<h3 id="{{.Anchor}}">{{.FullName}}</h3>
+{{- if .Comment}}
+<p class="comment">{{lines .Comment}}</p>
+{{- end}}
+<table>
+<tr><th>名称</th><th>值</th><th>说明</th></tr>
+{{- range .Values}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Number}}</td><td>{{lines .Comment}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+<hr>
+<p class="comment">Code generated by protoc-gen-grpc-tcpgw from {{.Source}}. DO NOT EDIT.</p>
+</body>
+</html>
+{{define "fields"}}
+{{- if .Fields -}}
+<table>
+<tr><th>字段</th><th>类型</th><th>说明</th></tr>
+{{- range .Fields}}
+<tr><td><code>{{.Name}}</code></td><td>{{if .Label}}{{.Label}} {{end}}{{if .Link}}<a href="#{{.Link}}"><code>{{.Type}}</code></a>{{else}}<code>{{.Type}}</code>{{end}}</td><td>{{lines .Comment}}</td></tr>
+{{- end}}
+</table>
+{{- else -}}
+<p>无字段</p>
+{{- end}}
+{{- end}}
+`))
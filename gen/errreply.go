@@ -0,0 +1,87 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: errreply.go
+ * @time: 2026/10/18 17:40
+ */
+package gen
+
+import (
+	`fmt`
+
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	`github.com/golang/protobuf/protoc-gen-go/generator`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 错误响应协议, 由TransmitError的code/message/details填充
+type errorReply struct {
+	*declaredMessage
+	CodeField    string // Go字段名
+	CodeType     string // Go类型
+	MsgField     string
+	DetailsField string
+}
+
+var codeGoTypes = map[descriptor2.FieldDescriptorProto_Type]string{
+	descriptor2.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptor2.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	descriptor2.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	descriptor2.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptor2.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	descriptor2.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptor2.FieldDescriptorProto_TYPE_SINT64:   "int64",
+	descriptor2.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	descriptor2.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptor2.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+}
+
+// 解析插件参数 error_reply, 协议需要有整数字段code, 可选的字符串字段message/msg及 repeated google.protobuf.Any details,
+// 且必须通过 @declare/declare 声明了id
+func (p *TcpGenerator) loadErrorReply(file *descriptor.File, declares []*declaredMessage) (*errorReply, error) {
+	if len(p.errorReply) < 1 {
+		return nil, nil
+	}
+	msg, err := p.reg.LookupMsg(file.GetPackage(), p.errorReply)
+	if err != nil {
+		return nil, fmt.Errorf("error_reply=%s: %v", p.errorReply, err)
+	}
+	var decl *declaredMessage
+	for _, d := range declares {
+		if d.FQMN() == msg.FQMN() {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, fmt.Errorf("error_reply=%s: message must be declared with an id (%s or parameter declare)", p.errorReply, TagDeclare)
+	}
+
+	reply := &errorReply{declaredMessage: decl}
+	for _, f := range msg.Fields {
+		repeated := f.GetLabel() == descriptor2.FieldDescriptorProto_LABEL_REPEATED
+		switch f.GetName() {
+		case "code":
+			if goType, ok := codeGoTypes[f.GetType()]; ok && !repeated {
+				reply.CodeField = generator.CamelCase(f.GetName())
+				reply.CodeType = goType
+			}
+		case "message", "msg":
+			if f.GetType() == descriptor2.FieldDescriptorProto_TYPE_STRING && !repeated && len(reply.MsgField) < 1 {
+				reply.MsgField = generator.CamelCase(f.GetName())
+			}
+		case "details":
+			if repeated && f.GetTypeName() == ".google.protobuf.Any" {
+				reply.DetailsField = generator.CamelCase(f.GetName())
+			}
+		}
+	}
+	if len(reply.CodeField) < 1 {
+		return nil, fmt.Errorf("error_reply=%s: message needs an integer field 'code'", p.errorReply)
+	}
+	return reply, nil
+}
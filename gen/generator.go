@@ -17,11 +17,11 @@ import (
 	`path`
 	`path/filepath`
 	`strings`
+	`time`
 
 	`github.com/golang/protobuf/proto`
 	plugingo `github.com/golang/protobuf/protoc-gen-go/plugin`
 	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
-	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/generator`
 )
 
 type pathType int
@@ -37,10 +37,86 @@ type TcpGenerator struct {
 	registerFuncSuffix string
 	pathType           pathType
 	DefinePrefix       string
+	lockFile           string        // id锁定文件, 为空则不检查
+	declares           []string      // 插件参数声明的协议, "package.Message=id"
+	defaultTimeout     time.Duration // 非流式方法的默认调用超时
+	errorReply         string        // 错误响应协议, "package.Message"
+	jsonOpts           jsonOptions
+	defaultAuth        string // 未设置@auth的方法的鉴权要求
+	goClient           bool   // 是否生成网关客户端
+	tsClient           bool   // 是否生成TypeScript客户端
+	csClient           bool   // 是否生成C#协议表
+	manifest           bool   // 是否生成路由清单
+	doc                bool   // 是否生成协议文档
+	asyncApi           bool   // 是否生成AsyncAPI文档
+}
+
+func (p *TcpGenerator) SetGoClient(on bool) {
+	p.goClient = on
+}
+
+func (p *TcpGenerator) SetTsClient(on bool) {
+	p.tsClient = on
+}
+
+func (p *TcpGenerator) SetCsClient(on bool) {
+	p.csClient = on
+}
+
+func (p *TcpGenerator) SetManifest(on bool) {
+	p.manifest = on
+}
+
+func (p *TcpGenerator) SetDoc(on bool) {
+	p.doc = on
+}
+
+func (p *TcpGenerator) SetAsyncApi(on bool) {
+	p.asyncApi = on
+}
+
+func (p *TcpGenerator) SetDefaultAuth(auth string) {
+	p.defaultAuth = auth
+}
+
+// codec 1(json)的输出选项
+func (p *TcpGenerator) SetJsonOptions(origName, emitDefaults, enumsAsInts bool) {
+	p.jsonOpts = jsonOptions{OrigName: origName, EmitDefaults: emitDefaults, EnumsAsInts: enumsAsInts}
+}
+
+func (p *TcpGenerator) SetErrorReply(name string) {
+	p.errorReply = name
+}
+
+func (p *TcpGenerator) SetDefaultTimeout(d time.Duration) {
+	p.defaultTimeout = d
+}
+
+// 声明错误/通知协议及其id, 每个生成文件都会注册
+func (p *TcpGenerator) AddDeclare(spec string) {
+	p.declares = append(p.declares, spec)
+}
+
+func (p *TcpGenerator) SetLockFile(name string) {
+	p.lockFile = name
 }
 
 func (p *TcpGenerator) Generate(targets []*descriptor.File) ([]*plugingo.CodeGeneratorResponse_File, error) {
 	// panic("implement me")
+	if err := p.validate(targets); err != nil {
+		return nil, err
+	}
+	var lock *lockFile
+	if len(p.lockFile) > 0 {
+		old, err := loadLockFile(p.lockFile)
+		if err != nil {
+			return nil, err
+		}
+		lock, err = old.check(targetNames(targets), collectLockRoutes(p.loadMethods(targets)))
+		if err != nil {
+			return nil, err
+		}
+	}
 	var files []*plugingo.CodeGeneratorResponse_File
 	for _, file := range targets {
 		code, err := p.generate(file)
@@ -62,6 +138,64 @@ func (p *TcpGenerator) Generate(targets []*descriptor.File) ([]*plugingo.CodeGen
 			Name:    proto.String(output),
 			Content: proto.String(string(formatted)),
 		})
+		if p.tsClient {
+			ts, err := p.generateTs(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.ts", base)),
+				Content: proto.String(ts),
+			})
+		}
+		if p.csClient {
+			cs, err := p.generateCs(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.cs", base)),
+				Content: proto.String(cs),
+			})
+		}
+		if p.manifest {
+			data, err := p.generateManifest(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.json", base)),
+				Content: proto.String(data),
+			})
+		}
+		if p.doc {
+			md, html, err := p.generateDoc(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.md", base)),
+				Content: proto.String(md),
+			}, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.html", base)),
+				Content: proto.String(html),
+			})
+		}
+		if p.asyncApi {
+			data, err := p.generateAsyncApi(file)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &plugingo.CodeGeneratorResponse_File{
+				Name:    proto.String(fmt.Sprintf("%s.pb.tcpgw.asyncapi.json", base)),
+				Content: proto.String(data),
+			})
+		}
+	}
+	if lock != nil {
+		if err := lock.save(p.lockFile); err != nil {
+			return nil, err
+		}
 	}
 	return files, nil
 }
@@ -74,6 +208,26 @@ func (p *TcpGenerator) generate(file *descriptor.File) (string, error) {
 		imports = append(imports, pkg)
 	}
 	path2Comments := p.reg.fileComments[*file.Name]
+	declares, err := p.loadDeclares(file)
+	if err != nil {
+		return "", err
+	}
+	pushes, err := p.loadPushes(file)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range append(append([]*declaredMessage{}, declares...), pushes...) {
+		pkg := d.File.GoPkg
+		if pkg == file.GoPkg || pkgSeen[pkg.Path] {
+			continue
+		}
+		pkgSeen[pkg.Path] = true
+		imports = append(imports, pkg)
+	}
+	errReply, err := p.loadErrorReply(file, declares)
+	if err != nil {
+		return "", err
+	}
 
 	// outComments := map[string]string{}
 	for _, svc := range file.Services {
@@ -94,7 +248,14 @@ func (p *TcpGenerator) generate(file *descriptor.File) (string, error) {
 		File:    file,
 		Imports: imports,
 		// RegisterFunSuffix: p.registerFuncSuffix,
-		DefinePrefix:     p.DefinePrefix,
+		DefinePrefix:   p.DefinePrefix,
+		Declares:       declares,
+		Pushes:         pushes,
+		GoClient:       p.goClient,
+		ErrorReply:     errReply,
+		DefaultTimeout: p.defaultTimeout,
+		Json:           p.jsonOpts,
+		DefaultAuth:    p.defaultAuth,
 	}
 	return applyTemplate(params, p.reg.commentsMap, path2Comments)
 }
@@ -119,18 +280,27 @@ func (p *TcpGenerator) addEnumPathParamImports(file *descriptor.File, m *descrip
 	return imports
 }
 
-func New(reg *Registry, registerFuncSuffix, pathTypeString, definePrefix string) generator.Generator {
+func New(reg *Registry, registerFuncSuffix, pathTypeString, definePrefix string) *TcpGenerator {
 	var imports []descriptor.GoPackage
 	for _, pkgpath := range []string{
+		"bytes",
+		"compress/gzip",
 		"context",
-		"encoding/json",
+		"crypto/rand",
+		"encoding/hex",
 		"errors",
+		"fmt",
+		"io",
 		"strings",
+		"sync",
 		"time",
 		"github.com/generalzgd/comm-libs",
+		"github.com/golang/protobuf/jsonpb",
 		"github.com/golang/protobuf/proto",
 		"google.golang.org/grpc",
+		"google.golang.org/grpc/connectivity",
 		"google.golang.org/grpc/metadata",
+		"google.golang.org/grpc/status",
 	} {
 		pkg := descriptor.GoPackage{
 			Path: pkgpath,
@@ -164,5 +334,6 @@ func New(reg *Registry, registerFuncSuffix, pathTypeString, definePrefix string)
 		registerFuncSuffix: registerFuncSuffix,
 		pathType:           pathType,
 		DefinePrefix:       definePrefix,
+		defaultTimeout:     5 * time.Second,
 	}
 }
@@ -0,0 +1,130 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: generator_test.go
+ * @time: 2026/10/19 15:30
+ */
+package gen
+
+import (
+	`fmt`
+	`strings`
+	`testing`
+
+	`github.com/golang/protobuf/proto`
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	plugin_go `github.com/golang/protobuf/protoc-gen-go/plugin`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 构造网关文件 p2.proto(go包p2): 协议 Req/Reply/Notice, 服务Gate的方法Send转发到Im,
+// svcComment为服务注释, noticeComment为Notice的注释; 返回生成的go代码
+func generateTestGate(t *testing.T, g func(*TcpGenerator), svcComment, noticeComment string) string {
+	code := &descriptor2.FieldDescriptorProto{
+		Name:     proto.String("code"),
+		Number:   proto.Int32(1),
+		Label:    descriptor2.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptor2.FieldDescriptorProto_TYPE_INT32.Enum(),
+		JsonName: proto.String("code"),
+	}
+	file := &descriptor2.FileDescriptorProto{
+		Name:    proto.String("p2.proto"),
+		Package: proto.String("p2"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor2.FileOptions{GoPackage: proto.String("p2")},
+		MessageType: []*descriptor2.DescriptorProto{
+			{Name: proto.String("Req")},
+			{Name: proto.String("Reply")},
+			{Name: proto.String("Notice"), Field: []*descriptor2.FieldDescriptorProto{code}},
+		},
+		Service: []*descriptor2.ServiceDescriptorProto{{
+			Name: proto.String("Gate"),
+			Method: []*descriptor2.MethodDescriptorProto{{
+				Name:       proto.String("Send"),
+				InputType:  proto.String(".p2.Req"),
+				OutputType: proto.String(".p2.Reply"),
+			}},
+		}},
+	}
+	comments := map[string]string{
+		fmt.Sprintf("%d,2", messagePath):                  noticeComment,
+		fmt.Sprintf("%d,0", servicePath):                  svcComment,
+		fmt.Sprintf("%d,0,%d,0", servicePath, methodPath): "// @transmit\n// @target Im\n// @upid 1\n// @downid 2",
+	}
+
+	reg := NewRegistry()
+	req := &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"p2.proto"},
+		ProtoFile:      []*descriptor2.FileDescriptorProto{file},
+	}
+	if err := reg.Load(req); err != nil {
+		t.Fatal(err)
+	}
+	reg.AddComments("p2.proto", comments)
+	if err := reg.ParseCommentsSource(req.ProtoFile); err != nil {
+		t.Fatal(err)
+	}
+	target, err := reg.LookupFile("p2.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := New(reg, "Handler", "", "")
+	if g != nil {
+		g(gen)
+	}
+	files, err := gen.Generate([]*descriptor.File{target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files[0].GetContent()
+}
+
+// 网关文件自身定义的@push协议不能带包名
+func TestGenerateSamePackagePush(t *testing.T) {
+	out := generateTestGate(t, nil, "", "// @push 6001")
+	if !strings.Contains(out, "id2struct[6001] = func() proto.Message { return &Notice{} }") {
+		t.Fatalf("push id2struct not found:\n%s", out)
+	}
+	if strings.Contains(out, "p2.Notice") {
+		t.Fatalf("same package type is qualified:\n%s", out)
+	}
+}
+
+// 网关文件自身定义的@declare协议(包括error_reply及客户端的错误解码)不能带包名
+func TestGenerateSamePackageDeclare(t *testing.T) {
+	out := generateTestGate(t, func(g *TcpGenerator) {
+		g.SetErrorReply("Notice")
+		g.SetGoClient(true)
+	}, "// @declare Notice 7001", "")
+	for _, want := range []string{
+		"id2struct[7001] = func() proto.Message { return &Notice{} }",
+		"reply := &Notice{\n", // NewErrorReply
+		"reply := &Notice{}",  // 客户端解码错误响应
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("%q not found:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "p2.Notice") {
+		t.Fatalf("same package type is qualified:\n%s", out)
+	}
+}
+
+// 客户端方法按@downid检查响应包的id
+func TestGenerateClientDownId(t *testing.T) {
+	out := generateTestGate(t, func(g *TcpGenerator) {
+		g.SetGoClient(true)
+	}, "", "")
+	for _, want := range []string{
+		"c.Invoke(ctx, 1, 2, req, resp)",
+		"if downId != 0 && pack.Id != downId {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("%q not found:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: lockfile.go
+ * @time: 2026/10/18 14:20
+ */
+package gen
+
+import (
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`os`
+	`sort`
+	`strings`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 锁定文件中的一条路由, 对应id2meth/structName2id中的数据
+type lockRoute struct {
+	Method   string `json:"method"` // package.TargetService/Method
+	UpId     uint16 `json:"up_id"`
+	DownId   uint16 `json:"down_id,omitempty"`
+	Request  string `json:"request"`
+	Response string `json:"response"`
+	File     string `json:"file,omitempty"` // 定义方法的proto文件
+}
+
+// id锁定文件, 保证已发布的id不会被改动或分配给其他方法/协议
+type lockFile struct {
+	Routes  []*lockRoute `json:"routes"`
+	Retired []*lockRoute `json:"retired,omitempty"` // 已删除方法占用过的id, 不能再使用
+}
+
+func loadLockFile(name string) (*lockFile, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return &lockFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := &lockFile{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return lock, nil
+}
+
+func (p *lockFile) save(name string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// 从待生成文件中收集当前的路由, 按@upid排序
+func collectLockRoutes(methods []*methodInfo) []*lockRoute {
+	var list []*lockRoute
+	for _, m := range methods {
+		if !m.CanOutput() {
+			continue
+		}
+		list = append(list, &lockRoute{
+			Method:   m.FullMethod(),
+			UpId:     m.GetUpId(),
+			DownId:   m.GetDownId(),
+			Request:  strings.TrimPrefix(m.RequestType.FQMN(), "."),
+			Response: strings.TrimPrefix(m.ResponseType.FQMN(), "."),
+			File:     m.File.GetName(),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpId < list[j].UpId
+	})
+	return list
+}
+
+// 本次生成的proto文件名
+func targetNames(targets []*descriptor.File) []string {
+	var list []string
+	for _, file := range targets {
+		list = append(list, file.GetName())
+	}
+	return list
+}
+
+// 与锁定文件对比: 已有/已删除方法的id不能变, 旧id不能分配给其他方法或协议
+// files为本次生成的文件, 只有这些文件中消失的方法才会被标记为已删除, 其他文件的路由原样保留
+// 返回更新后的锁定内容
+func (p *lockFile) check(files []string, routes []*lockRoute) (*lockFile, error) {
+	var errs []string
+	targets := map[string]bool{}
+	for _, name := range files {
+		targets[name] = true
+	}
+	oldRoutes := map[string]*lockRoute{}
+	upOwner := map[uint16]*lockRoute{} // @upid => 曾经使用的方法
+	idMsg := map[uint16]*lockRoute{}   // id => 曾经绑定的协议
+	for _, list := range [][]*lockRoute{p.Retired, p.Routes} {
+		for _, r := range list {
+			oldRoutes[r.Method] = r
+			upOwner[r.UpId] = r
+			idMsg[r.UpId] = r
+			if r.DownId > 0 {
+				idMsg[r.DownId] = r
+			}
+		}
+	}
+	msgOf := func(r *lockRoute, id uint16) string {
+		if id == r.UpId {
+			return r.Request
+		}
+		return r.Response
+	}
+
+	current := map[string]bool{}
+	for _, r := range routes {
+		current[r.Method] = true
+		if old, ok := oldRoutes[r.Method]; ok {
+			if old.UpId != r.UpId {
+				errs = append(errs, fmt.Sprintf("%s: %s changed from %d to %d", r.Method, TagUpId, old.UpId, r.UpId))
+			}
+			if old.DownId > 0 && old.DownId != r.DownId {
+				errs = append(errs, fmt.Sprintf("%s: %s changed from %d to %d", r.Method, TagDownId, old.DownId, r.DownId))
+			}
+		}
+		if prev, ok := upOwner[r.UpId]; ok && prev.Method != r.Method {
+			errs = append(errs, fmt.Sprintf("%s: %s %d was assigned to %s", r.Method, TagUpId, r.UpId, prev.Method))
+		}
+		for _, id := range []uint16{r.UpId, r.DownId} {
+			if id == 0 {
+				continue
+			}
+			if prev, ok := idMsg[id]; ok && msgOf(prev, id) != msgOf(r, id) {
+				errs = append(errs, fmt.Sprintf("%s: id %d was bound to %s by %s", r.Method, id, msgOf(prev, id), prev.Method))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("id lock check fail:\n" + strings.Join(errs, "\n"))
+	}
+
+	next := &lockFile{
+		Routes: routes,
+	}
+	for _, r := range p.Retired {
+		if !current[r.Method] {
+			next.Retired = append(next.Retired, r)
+		}
+	}
+	for _, r := range p.Routes {
+		switch {
+		case current[r.Method]:
+		case targets[r.File]:
+			next.Retired = append(next.Retired, r)
+		default:
+			// 未参与本次生成的文件(或旧锁定文件中没有记录文件)的路由保持不变
+			next.Routes = append(next.Routes, r)
+		}
+	}
+	for _, list := range [][]*lockRoute{next.Routes, next.Retired} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].UpId < list[j].UpId
+		})
+	}
+	return next, nil
+}
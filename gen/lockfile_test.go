@@ -0,0 +1,142 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: lockfile_test.go
+ * @time: 2026/10/19 10:20
+ */
+package gen
+
+import (
+	`strings`
+	`testing`
+)
+
+func route(file, method string, upId, downId uint16) *lockRoute {
+	name := method[strings.Index(method, "/")+1:]
+	return &lockRoute{
+		Method:   method,
+		UpId:     upId,
+		DownId:   downId,
+		Request:  "im." + name + "Request",
+		Response: "im." + name + "Reply",
+		File:     file,
+	}
+}
+
+func TestLockFileCheck(t *testing.T) {
+	base := &lockFile{
+		Routes: []*lockRoute{
+			route("a.proto", "im.Chat/Send", 1001, 1002),
+			route("b.proto", "im.User/Login", 2001, 2002),
+		},
+		Retired: []*lockRoute{
+			route("a.proto", "im.Chat/Recall", 1003, 1004),
+		},
+	}
+	tests := []struct {
+		name    string
+		files   []string
+		routes  []*lockRoute
+		wantErr string // 为空表示通过
+	}{
+		{
+			name:  "unchanged",
+			files: []string{"a.proto", "b.proto"},
+			routes: []*lockRoute{
+				route("a.proto", "im.Chat/Send", 1001, 1002),
+				route("b.proto", "im.User/Login", 2001, 2002),
+			},
+		},
+		{
+			name:    "upid changed",
+			files:   []string{"a.proto", "b.proto"},
+			routes:  []*lockRoute{route("a.proto", "im.Chat/Send", 1005, 1002)},
+			wantErr: "im.Chat/Send: @upid changed from 1001 to 1005",
+		},
+		{
+			name:    "downid changed",
+			files:   []string{"a.proto"},
+			routes:  []*lockRoute{route("a.proto", "im.Chat/Send", 1001, 1006)},
+			wantErr: "im.Chat/Send: @downid changed from 1002 to 1006",
+		},
+		{
+			name:    "upid reused by other method",
+			files:   []string{"a.proto"},
+			routes:  []*lockRoute{route("a.proto", "im.Chat/Edit", 1003, 0)},
+			wantErr: "im.Chat/Edit: @upid 1003 was assigned to im.Chat/Recall",
+		},
+		{
+			name:    "id bound to other message",
+			files:   []string{"a.proto"},
+			routes:  []*lockRoute{route("a.proto", "im.Chat/Edit", 1010, 2002)},
+			wantErr: "id 2002 was bound to im.LoginReply by im.User/Login",
+		},
+		{
+			name:    "retired method back with new id",
+			files:   []string{"a.proto"},
+			routes:  []*lockRoute{route("a.proto", "im.Chat/Recall", 1010, 1004)},
+			wantErr: "im.Chat/Recall: @upid changed from 1003 to 1010",
+		},
+		{
+			name:   "retired method back with same id",
+			files:  []string{"a.proto"},
+			routes: []*lockRoute{route("a.proto", "im.Chat/Recall", 1003, 1004)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := base.check(tt.files, tt.routes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 只生成a.proto时, b.proto的路由不能被当作已删除, 之后修改b.proto的id仍要报错
+func TestLockFilePartialGenerate(t *testing.T) {
+	lock := &lockFile{
+		Routes: []*lockRoute{
+			route("a.proto", "im.Chat/Send", 1001, 1002),
+			route("a.proto", "im.Chat/Recall", 1003, 0),
+			route("b.proto", "im.User/Login", 2001, 2002),
+		},
+	}
+
+	next, err := lock.check([]string{"a.proto"}, []*lockRoute{
+		route("a.proto", "im.Chat/Send", 1001, 1002),
+	})
+	if err != nil {
+		t.Fatalf("partial check() error = %v", err)
+	}
+	var routes, retired []string
+	for _, r := range next.Routes {
+		routes = append(routes, r.Method)
+	}
+	for _, r := range next.Retired {
+		retired = append(retired, r.Method)
+	}
+	if got, want := strings.Join(routes, ","), "im.Chat/Send,im.User/Login"; got != want {
+		t.Fatalf("routes = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(retired, ","), "im.Chat/Recall"; got != want {
+		t.Fatalf("retired = %s, want %s", got, want)
+	}
+
+	_, err = next.check([]string{"b.proto"}, []*lockRoute{
+		route("b.proto", "im.User/Login", 2005, 2002),
+	})
+	if err == nil || !strings.Contains(err.Error(), "im.User/Login: @upid changed from 2001 to 2005") {
+		t.Fatalf("check() after partial generate error = %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: manifest.go
+ * @time: 2026/10/19 00:10
+ */
+package gen
+
+import (
+	`encoding/json`
+	`strings`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 路由清单(.pb.tcpgw.json), 供网关配置加载、运维面板及测试工具读取
+type manifest struct {
+	Source     string             `json:"source"`
+	Package    string             `json:"package"`
+	GoPackage  string             `json:"go_package"`
+	Routes     []*manifestRoute   `json:"routes"`
+	Pushes     []*manifestMessage `json:"pushes"`
+	Declares   []*manifestMessage `json:"declares"`
+	ErrorReply *manifestMessage   `json:"error_reply,omitempty"`
+}
+
+type manifestRoute struct {
+	UpId     uint16         `json:"up_id"`
+	DownId   uint16         `json:"down_id"`
+	Method   string         `json:"method"` // 与GetMethById的返回值一致
+	Gateway  manifestMethod `json:"gateway"`
+	Target   manifestMethod `json:"target"`
+	Stream   string         `json:"stream"` // unary|server|client|bidi
+	Request  string         `json:"request"`
+	Response string         `json:"response"`
+	Comment  string         `json:"comment"`
+}
+
+type manifestMethod struct {
+	Package string `json:"package"`
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type manifestMessage struct {
+	Id      uint16 `json:"id"`
+	Message string `json:"message"`
+}
+
+// 去掉注释中的标签行及注释符号, 与协议文档中的方法说明一致
+func manifestComment(m *methodWithComment) string {
+	var li []string
+	for _, line := range strings.Split(m.GetFormatComment(), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		// @tarpkg/@import等其他标签行
+		if len(line) > 0 && !strings.HasPrefix(line, "@") {
+			li = append(li, line)
+		}
+	}
+	return strings.Join(li, "\n")
+}
+
+func streamType(m *methodWithComment) string {
+	switch {
+	case m.IsBidiStream():
+		return "bidi"
+	case m.IsServerStream():
+		return "server"
+	case m.IsClientStream():
+		return "client"
+	}
+	return "unary"
+}
+
+func (p *TcpGenerator) generateManifest(file *descriptor.File) (string, error) {
+	routes, err := p.loadRoutes(file)
+	if err != nil {
+		return "", err
+	}
+	out := &manifest{
+		Source:    file.GetName(),
+		Package:   file.GetPackage(),
+		GoPackage: file.GoPkg.Path,
+		Routes:    []*manifestRoute{},
+		Pushes:    []*manifestMessage{},
+		Declares:  []*manifestMessage{},
+	}
+	for _, m := range routes.Methods {
+		out.Routes = append(out.Routes, &manifestRoute{
+			UpId:   m.GetUpId(),
+			DownId: m.GetDownId(),
+			Method: m.FullMethod,
+			Gateway: manifestMethod{
+				Package: file.GetPackage(),
+				Service: m.Service.GetName(),
+				Method:  m.GetName(),
+			},
+			Target: manifestMethod{
+				Package: strings.TrimSuffix(m.GetTargetSvrPackage(), "."),
+				Service: m.GetTargetSvrName(),
+				Method:  m.GetName(),
+			},
+			Stream:   streamType(m.methodWithComment),
+			Request:  msgFullName(m.Request),
+			Response: msgFullName(m.Response),
+			Comment:  manifestComment(m.methodWithComment),
+		})
+	}
+	for _, d := range routes.Pushes {
+		out.Pushes = append(out.Pushes, &manifestMessage{Id: d.Id, Message: msgFullName(d.Message)})
+	}
+	for _, d := range routes.Declares {
+		out.Declares = append(out.Declares, &manifestMessage{Id: d.Id, Message: msgFullName(d.Message)})
+	}
+	if reply := routes.ErrorReply; reply != nil {
+		out.ErrorReply = &manifestMessage{Id: reply.Id, Message: msgFullName(reply.Message)}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
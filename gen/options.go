@@ -0,0 +1,52 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: options.go
+ * @time: 2026/10/18 10:12
+ */
+package gen
+
+import (
+	`github.com/golang/protobuf/proto`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+
+	`github.com/generalzgd/protoc-gen-grpc-tcpgw/options`
+)
+
+// 读取方法上的 option (tcpgw.route), 未设置返回nil
+func getMethodRoute(meth *descriptor.Method) *options.Route {
+	opts := meth.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_Route) {
+		return nil
+	}
+	ext, err := proto.GetExtension(opts, options.E_Route)
+	if err != nil {
+		return nil
+	}
+	route, ok := ext.(*options.Route)
+	if !ok {
+		return nil
+	}
+	return route
+}
+
+// 读取服务上的 option (tcpgw.service_route), 未设置返回nil
+func getServiceRoute(svc *descriptor.Service) *options.ServiceRoute {
+	opts := svc.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_ServiceRoute) {
+		return nil
+	}
+	ext, err := proto.GetExtension(opts, options.E_ServiceRoute)
+	if err != nil {
+		return nil
+	}
+	route, ok := ext.(*options.ServiceRoute)
+	if !ok {
+		return nil
+	}
+	return route
+}
@@ -0,0 +1,83 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: push.go
+ * @time: 2026/10/18 21:20
+ */
+package gen
+
+import (
+	`errors`
+	`fmt`
+	`strconv`
+	`strings`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 解析协议注释中的 @push, 未设置返回0
+func parsePushTag(comment string) (uint16, error) {
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		fields := strings.Fields(line)
+		if len(fields) < 1 || fields[0] != TagPush {
+			continue
+		}
+		if len(fields) < 2 {
+			return 0, fmt.Errorf("bad tag syntax %q, want '%s 5001'", line, TagPush)
+		}
+		v, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("bad %s value %q", TagPush, fields[1])
+		}
+		if v < 1 || v > maxRouteId {
+			return 0, fmt.Errorf("%s %d out of range [1, %d]", TagPush, v, maxRouteId)
+		}
+		return uint16(v), nil
+	}
+	return 0, nil
+}
+
+// 收集文件及其依赖中带有 @push 注释的(顶层)协议
+func (p *TcpGenerator) loadPushes(file *descriptor.File) ([]*declaredMessage, error) {
+	var list []*declaredMessage
+	var errs []string
+	seen := map[string]bool{}
+	queue := []*descriptor.File{file}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if seen[f.GetName()] {
+			continue
+		}
+		seen[f.GetName()] = true
+		comments := p.reg.fileComments[f.GetName()]
+		for _, msg := range f.Messages {
+			if len(msg.Outers) > 0 {
+				continue
+			}
+			path := fmt.Sprintf("%d,%d", messagePath, msg.Index)
+			id, err := parsePushTag(comments[path])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s: %v", p.reg.SourcePos(f.GetName(), path), msg.GetName(), err))
+				continue
+			}
+			if id > 0 {
+				list = append(list, &declaredMessage{Message: msg, Id: id, Pos: p.reg.SourcePos(f.GetName(), path), OutFile: file})
+			}
+		}
+		for _, dep := range f.GetDependency() {
+			if d, err := p.reg.LookupFile(dep); err == nil {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return list, nil
+}
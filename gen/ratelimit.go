@@ -0,0 +1,104 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: ratelimit.go
+ * @time: 2026/10/18 20:10
+ */
+package gen
+
+import (
+	`fmt`
+	`math`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// 方法限流配置, 令牌桶
+type rateLimitConf struct {
+	Rate  float64 // 每秒产生的令牌数
+	Burst int     // 桶容量
+	By    string  // 按该metadata的值分别限流, 为空则整个方法共用一个桶
+}
+
+// 生成代码中的速率表达式
+func (p *rateLimitConf) RateExpr() string {
+	return strconv.FormatFloat(p.Rate, 'g', -1, 64)
+}
+
+// 解析注释中的 @ratelimit, 未设置返回nil
+func parseRateLimitTag(lines []string) (*rateLimitConf, error) {
+	for _, line := range lines {
+		if !strings.Contains(line, TagRateLimit) {
+			continue
+		}
+		if !strings.HasPrefix(line, TagRateLimit) {
+			return nil, fmt.Errorf("bad tag syntax %q, want '%s 10/s burst=20 by=uid'", line, TagRateLimit)
+		}
+		return parseRateLimit(strings.TrimPrefix(line, TagRateLimit))
+	}
+	return nil, nil
+}
+
+// 解析 "10/s [burst=20] [by=uid]", 周期可为 s/m/h 或时长(如 100ms), burst默认为每周期的数量
+func parseRateLimit(value string) (*rateLimitConf, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 1 {
+		return nil, nil
+	}
+	spec := strings.SplitN(fields[0], "/", 2)
+	if len(spec) != 2 {
+		return nil, fmt.Errorf("bad ratelimit %q, want '10/s'", fields[0])
+	}
+	n, err := strconv.ParseFloat(spec[0], 64)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("bad ratelimit count %q", spec[0])
+	}
+	unit := spec[1]
+	if len(unit) > 0 && (unit[0] < '0' || unit[0] > '9') {
+		unit = "1" + unit
+	}
+	period, err := time.ParseDuration(unit)
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("bad ratelimit period %q", spec[1])
+	}
+	conf := &rateLimitConf{
+		Rate:  n / period.Seconds(),
+		Burst: int(math.Ceil(n)),
+	}
+	for _, it := range fields[1:] {
+		kv := strings.SplitN(it, "=", 2)
+		if len(kv) != 2 || len(kv[1]) < 1 {
+			return nil, fmt.Errorf("bad ratelimit option %q", it)
+		}
+		switch kv[0] {
+		case "burst":
+			v, err := strconv.Atoi(kv[1])
+			if err != nil || v < 1 {
+				return nil, fmt.Errorf("bad ratelimit burst %q", kv[1])
+			}
+			conf.Burst = v
+		case "by":
+			// metadata的key均为小写
+			conf.By = strings.ToLower(kv[1])
+		default:
+			return nil, fmt.Errorf("unknown ratelimit option %q, want burst or by", kv[0])
+		}
+	}
+	return conf, nil
+}
+
+// 方法限流配置: 方法选项 > 方法注释 @ratelimit, 未设置返回nil
+func (p *methodWithComment) GetRateLimit() *rateLimitConf {
+	if p.Route != nil {
+		if c, _ := parseRateLimit(p.Route.Ratelimit); c != nil {
+			return c
+		}
+	}
+	c, _ := parseRateLimitTag(p.CommentList)
+	return c
+}
@@ -0,0 +1,78 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: ratelimit_test.go
+ * @time: 2026/10/19 11:10
+ */
+package gen
+
+import (
+	`testing`
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *rateLimitConf
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "10/s", want: &rateLimitConf{Rate: 10, Burst: 10}},
+		{value: "10/100ms", want: &rateLimitConf{Rate: 100, Burst: 10}},
+		{value: "60/m burst=5", want: &rateLimitConf{Rate: 1, Burst: 5}},
+		{value: "1.5/s", want: &rateLimitConf{Rate: 1.5, Burst: 2}},
+		{value: " 10/s by=UID ", want: &rateLimitConf{Rate: 10, Burst: 10, By: "uid"}},
+		{value: "10", wantErr: true},
+		{value: "0/s", wantErr: true},
+		{value: "-1/s", wantErr: true},
+		{value: "10/x", wantErr: true},
+		{value: "10/0s", wantErr: true},
+		{value: "10/s burst=0", wantErr: true},
+		{value: "10/s burst=", wantErr: true},
+		{value: "10/s by", wantErr: true},
+		{value: "10/s size=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRateLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseRateLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRateLimitTag(t *testing.T) {
+	tests := []struct {
+		lines   []string
+		want    *rateLimitConf
+		wantErr bool
+	}{
+		{lines: []string{"发送", "@upid 1"}, want: nil},
+		{lines: []string{"@ratelimit 5/s by=uid"}, want: &rateLimitConf{Rate: 5, Burst: 5, By: "uid"}},
+		{lines: []string{"限流 @ratelimit 5/s"}, wantErr: true},
+		{lines: []string{"@ratelimit 5/s burst=0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRateLimitTag(tt.lines)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRateLimitTag(%q) error = %v, wantErr %v", tt.lines, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseRateLimitTag(%q) = %+v, want %+v", tt.lines, got, tt.want)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 type Registry struct {
 	*descriptor.Registry
 	fileComments map[string]map[string]string
+	fileLines    map[string]map[string]int // file => path => 行号(从1开始)
 	commentsMap  map[string]string
 }
 
@@ -29,6 +30,7 @@ func NewRegistry() *Registry {
 	return &Registry{
 		Registry:     descriptor.NewRegistry(),
 		fileComments: map[string]map[string]string{},
+		fileLines:    map[string]map[string]int{},
 		commentsMap:  map[string]string{},
 	}
 }
@@ -37,6 +39,18 @@ func (p *Registry) AddComments(key string, comments map[string]string) {
 	p.fileComments[key] = comments
 }
 
+func (p *Registry) AddLines(key string, lines map[string]int) {
+	p.fileLines[key] = lines
+}
+
+// 返回 file:line 形式的源码位置, 没有位置信息时只返回文件名
+func (p *Registry) SourcePos(file, path string) string {
+	if line, ok := p.fileLines[file][path]; ok {
+		return fmt.Sprintf("%s:%d", file, line)
+	}
+	return file
+}
+
 const (
 	// packagePath = 2 //
 	messagePath = 4 // message type
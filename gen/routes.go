@@ -0,0 +1,104 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: routes.go
+ * @time: 2026/10/18 23:05
+ */
+package gen
+
+import (
+	`sort`
+	`strings`
+
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+// 输出到其他语言/文档的路由, 由collectServices收集的方法生成
+type routeMethod struct {
+	*methodWithComment
+	FullMethod string // package.TargetService/Method, 与id2meth一致
+	Request    *descriptor.Message
+	Response   *descriptor.Message
+}
+
+// id => 协议
+type routeId struct {
+	Id      uint16
+	Message *descriptor.Message
+}
+
+func (p *routeId) FullName() string {
+	return msgFullName(p.Message)
+}
+
+// 协议全名, package.Message
+func msgFullName(msg *descriptor.Message) string {
+	return strings.TrimPrefix(msg.FQMN(), ".")
+}
+
+// 一个文件的路由数据, 与生成的Go代码使用相同的来源
+type routeData struct {
+	File       *descriptor.File
+	Methods    []*routeMethod // 带有@upid的转发方法, 顺序与proto定义一致
+	Declares   []*declaredMessage
+	Pushes     []*declaredMessage
+	ErrorReply *errorReply
+}
+
+func (p *TcpGenerator) loadRoutes(file *descriptor.File) (*routeData, error) {
+	declares, err := p.loadDeclares(file)
+	if err != nil {
+		return nil, err
+	}
+	pushes, err := p.loadPushes(file)
+	if err != nil {
+		return nil, err
+	}
+	errReply, err := p.loadErrorReply(file, declares)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &routeData{File: file, Declares: declares, Pushes: pushes, ErrorReply: errReply}
+	params := param{File: file, DefaultTimeout: p.defaultTimeout, DefaultAuth: p.defaultAuth}
+	for _, svc := range collectServices(&params, p.reg.commentsMap, p.reg.fileComments[file.GetName()]) {
+		for _, m := range svc.MethodsWithComment {
+			if !m.CanOutput() || m.GetUpId() == 0 {
+				continue
+			}
+			data.Methods = append(data.Methods, &routeMethod{
+				methodWithComment: m,
+				FullMethod:        file.GoPkg.Name + "." + m.GetTargetSvrName() + "/" + m.GetName(),
+				Request:           m.RequestType,
+				Response:          m.ResponseType,
+			})
+		}
+	}
+	return data, nil
+}
+
+// 所有id对应的协议(请求/响应/推送/声明), 按id排序. 校验已保证同一id只对应一个协议
+func (p *routeData) Ids() []*routeId {
+	id2msg := map[uint16]*descriptor.Message{}
+	for _, m := range p.Methods {
+		id2msg[m.GetUpId()] = m.Request
+		if id := m.GetDownId(); id > 0 {
+			id2msg[id] = m.Response
+		}
+	}
+	for _, d := range append(append([]*declaredMessage{}, p.Declares...), p.Pushes...) {
+		id2msg[d.Id] = d.Message
+	}
+	var list []*routeId
+	for id, msg := range id2msg {
+		list = append(list, &routeId{Id: id, Message: msg})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
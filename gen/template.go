@@ -12,23 +12,34 @@ package gen
 
 import (
 	`bytes`
+	`fmt`
+	`sort`
 	"strconv"
 	"strings"
 	`text/template`
+	`time`
 
 	`github.com/golang/protobuf/protoc-gen-go/generator`
 	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
 	`github.com/toolkits/slice`
+
+	`github.com/generalzgd/protoc-gen-grpc-tcpgw/options`
 )
 
 const (
-	TagImport   = "@import"
-	TagTransmit = "@transmit"
-	TagTarget   = "@target"
-	TagTarPkg   = "@tarpkg"
-	TagId       = "@id"     // 上行请求协议对应的id
-	TagUpId     = "@upid"   // 上行请求协议对应的id
-	TagDownId   = "@downid" // 下行响应协议对应的id
+	TagImport    = "@import"
+	TagTransmit  = "@transmit"
+	TagTarget    = "@target"
+	TagTarPkg    = "@tarpkg"
+	TagId        = "@id"        // 上行请求协议对应的id
+	TagUpId      = "@upid"      // 上行请求协议对应的id
+	TagDownId    = "@downid"    // 下行响应协议对应的id
+	TagDeclare   = "@declare"   // 服务注释中声明错误/通知协议及其id, 例: @declare comm.Error 8197
+	TagTimeout   = "@timeout"   // 调用超时, 方法或服务注释, 例: @timeout 800ms
+	TagCompress  = "@compress"  // 响应超过阈值时压缩, 例: @compress 4k gzip
+	TagRateLimit = "@ratelimit" // 令牌桶限流, 例: @ratelimit 10/s burst=20 by=uid
+	TagAuth      = "@auth"      // 鉴权要求, 方法或服务注释, 例: @auth none|required|role:admin
+	TagPush      = "@push"      // 协议注释中声明服务端推送协议的id, 例: @push 5001
 )
 
 type param struct {
@@ -37,7 +48,21 @@ type param struct {
 	// RegisterFunSuffix string
 	WithTransmitArgs bool
 	DefinePrefix     string
-	AdditionImports     []string
+	AdditionImports  []string
+	Declares         []*declaredMessage
+	Pushes           []*declaredMessage // 带有@push的协议
+	GoClient         bool               // 插件参数 go_client, 生成网关客户端
+	ErrorReply       *errorReply
+	DefaultTimeout   time.Duration // 插件参数 timeout
+	DefaultAuth      string        // 插件参数 auth
+	Json             jsonOptions
+}
+
+// 插件参数 json_orig_name/json_emit_defaults/json_enums_as_ints, 对应jsonpb.Marshaler的选项
+type jsonOptions struct {
+	OrigName     bool // 使用proto中的字段名, 否则为lowerCamelCase
+	EmitDefaults bool // 输出零值字段
+	EnumsAsInts  bool // 枚举输出为数字, 否则为名称
 }
 
 type defParam struct {
@@ -45,6 +70,13 @@ type defParam struct {
 	// Services []*descriptor.Service
 	ServicesWithComment []*serviceWithComment
 	DefinePrefix        string
+	WithClientStream    bool // 是否存在客户端流/双向流方法
+	Declares            []*declaredMessage
+	Pushes              []*declaredMessage
+	ClientMethods       []*methodWithComment // 客户端的单次调用方法, 按定义顺序
+	ErrorReply          *errorReply          // 插件参数 error_reply, 可为空
+	Json                jsonOptions
+	WithSnappy          bool // 是否有方法使用 @compress snappy
 }
 
 type serviceWithComment struct {
@@ -107,8 +139,13 @@ func (p *serviceWithComment) GetFormatComment() string {
 
 type methodWithComment struct {
 	*descriptor.Method
-	Comment     string
-	CommentList []string
+	Comment        string
+	CommentList    []string
+	Route          *options.Route        // option (tcpgw.route), 优先于注释标签
+	SvcRoute       *options.ServiceRoute // option (tcpgw.service_route), 服务级默认值
+	SvcCommentList []string              // 所在服务的注释, 服务级默认值
+	DefTimeout     time.Duration         // 插件参数 timeout
+	DefAuth        string                // 插件参数 auth
 }
 
 func (p *methodWithComment) ParseComment() {
@@ -127,7 +164,9 @@ func (p *methodWithComment) GetFormatComment() string {
 	for _, line := range p.CommentList {
 		if strings.Contains(line, TagTransmit) || strings.Contains(line, TagTarget) ||
 			strings.Contains(line, TagId) || strings.Contains(line, TagUpId) ||
-			strings.Contains(line, TagDownId) {
+			strings.Contains(line, TagDownId) || strings.Contains(line, TagTimeout) ||
+			strings.Contains(line, TagCompress) || strings.Contains(line, TagRateLimit) ||
+			slice.ContainsString(strings.Fields(line), TagAuth) {
 			continue
 		}
 		li = append(li, "// "+line)
@@ -139,12 +178,30 @@ func (p *methodWithComment) CanOutput() bool {
 	if p.CommentList == nil {
 		p.ParseComment()
 	}
+	if p.Route != nil {
+		return true
+	}
 	if !slice.ContainsString(p.CommentList, TagTransmit) {
 		return false
 	}
 	return true
 }
 
+// 服务端流式返回(returns (stream Xxx))
+func (p *methodWithComment) IsServerStream() bool {
+	return p.GetServerStreaming() && !p.GetClientStreaming()
+}
+
+// 客户端流式请求((stream Xxx) returns (Yyy))
+func (p *methodWithComment) IsClientStream() bool {
+	return p.GetClientStreaming() && !p.GetServerStreaming()
+}
+
+// 双向流((stream Xxx) returns (stream Yyy))
+func (p *methodWithComment) IsBidiStream() bool {
+	return p.GetClientStreaming() && p.GetServerStreaming()
+}
+
 func (p *methodWithComment) GetRequestPackage() string {
 	pkg := p.RequestType.File.GetPackage()
 	if len(pkg) > 0 {
@@ -163,6 +220,9 @@ func (p *methodWithComment) GetResponsePackage() string {
 
 func (p *methodWithComment) GetTargetSvrName() string {
 	if p.CanOutput() {
+		if p.Route != nil && len(p.Route.Target) > 0 {
+			return generator.CamelCase(p.Route.Target)
+		}
 		tarCommentLine := ""
 		for _, it := range p.CommentList {
 			if strings.Contains(it, TagTarget) {
@@ -178,12 +238,18 @@ func (p *methodWithComment) GetTargetSvrName() string {
 				return generator.CamelCase(tar)
 			}
 		}
+		if p.SvcRoute != nil && len(p.SvcRoute.Target) > 0 {
+			return generator.CamelCase(p.SvcRoute.Target)
+		}
 	}
 	return *p.Service.Name // 默认返回当前服务名
 }
 
 func (p *methodWithComment) GetTargetSvrPackage() string {
 	if p.CanOutput() {
+		if p.Route != nil && len(p.Route.TarPkg) > 0 {
+			return p.Route.TarPkg + "."
+		}
 		tarPkg := ""
 		for _, it := range p.CommentList {
 			if strings.Contains(it, TagTarPkg) {
@@ -198,12 +264,18 @@ func (p *methodWithComment) GetTargetSvrPackage() string {
 				return tar + "."
 			}
 		}
+		if p.SvcRoute != nil && len(p.SvcRoute.TarPkg) > 0 {
+			return p.SvcRoute.TarPkg + "."
+		}
 	}
 	return ""
 }
 
 func (p *methodWithComment) GetUpId() uint16 {
 	if p.CanOutput() {
+		if p.Route != nil && p.Route.UpId > 0 {
+			return uint16(p.Route.UpId)
+		}
 		idCommentLine := ""
 		tag := TagId
 		for _, it := range p.CommentList {
@@ -232,6 +304,9 @@ func (p *methodWithComment) GetUpId() uint16 {
 
 func (p *methodWithComment) GetDownId() uint16 {
 	if p.CanOutput() {
+		if p.Route != nil && p.Route.DownId > 0 {
+			return uint16(p.Route.DownId)
+		}
 		idCommentLine := ""
 		for _, it := range p.CommentList {
 			if strings.Contains(it, TagDownId) {
@@ -251,8 +326,150 @@ func (p *methodWithComment) GetDownId() uint16 {
 	return 0
 }
 
-func applyTemplate(p param, name2Path map[string]string, path2Comment map[string]string) (string, error) {
-	out := bytes.NewBuffer(nil)
+// 解析注释中的 @timeout, 未设置返回0
+func parseTimeoutTag(lines []string) (time.Duration, error) {
+	value, line, found := findTagValue(lines, TagTimeout)
+	if !found {
+		return 0, nil
+	}
+	if !strings.HasPrefix(line, TagTimeout) {
+		return 0, fmt.Errorf("bad tag syntax %q, want '%s 800ms'", line, TagTimeout)
+	}
+	return parseTimeout(value)
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	if len(value) < 1 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("bad timeout value %q", value)
+	}
+	return d, nil
+}
+
+// 响应压缩配置
+type compressConf struct {
+	Threshold int    // 编码后超过该字节数才压缩
+	Flag      string // 生成代码中的压缩标记常量名
+}
+
+// 压缩算法 => 生成代码中的codec标记
+var compressFlags = map[string]string{
+	"gzip":   "CodecFlagGzip",
+	"snappy": "CodecFlagSnappy",
+}
+
+// 使用snappy压缩时生成代码引入的包
+const snappyImport = "github.com/golang/snappy"
+
+// 解析注释中的 @compress, 未设置返回nil
+func parseCompressTag(lines []string) (*compressConf, error) {
+	for _, line := range lines {
+		if !strings.Contains(line, TagCompress) {
+			continue
+		}
+		if !strings.HasPrefix(line, TagCompress) {
+			return nil, fmt.Errorf("bad tag syntax %q, want '%s 4k gzip'", line, TagCompress)
+		}
+		return parseCompress(strings.TrimPrefix(line, TagCompress))
+	}
+	return nil, nil
+}
+
+// 解析 "<阈值> [gzip|snappy]", 阈值单位可为 k/m, 默认gzip
+func parseCompress(value string) (*compressConf, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 1 {
+		return nil, nil
+	}
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("bad compress value %q", value)
+	}
+	num := strings.TrimSuffix(strings.ToLower(fields[0]), "b")
+	unit := 1
+	switch {
+	case strings.HasSuffix(num, "k"):
+		unit, num = 1<<10, strings.TrimSuffix(num, "k")
+	case strings.HasSuffix(num, "m"):
+		unit, num = 1<<20, strings.TrimSuffix(num, "m")
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("bad compress threshold %q", fields[0])
+	}
+	algo := "gzip"
+	if len(fields) > 1 {
+		algo = strings.ToLower(fields[1])
+	}
+	flag, ok := compressFlags[algo]
+	if !ok {
+		return nil, fmt.Errorf("bad compress algorithm %q, want gzip or snappy", fields[1])
+	}
+	return &compressConf{Threshold: n * unit, Flag: flag}, nil
+}
+
+// 响应压缩配置: 方法选项 > 方法注释 @compress, 未设置返回nil
+func (p *methodWithComment) GetCompress() *compressConf {
+	if p.Route != nil {
+		if c, _ := parseCompress(p.Route.Compress); c != nil {
+			return c
+		}
+	}
+	c, _ := parseCompressTag(p.CommentList)
+	return c
+}
+
+// 方法级超时: 方法选项 > 方法注释 @timeout
+func (p *methodWithComment) getMethodTimeout() time.Duration {
+	if p.Route != nil {
+		if d, _ := parseTimeout(p.Route.Timeout); d > 0 {
+			return d
+		}
+	}
+	d, _ := parseTimeoutTag(p.CommentList)
+	return d
+}
+
+// 非流式方法的调用超时: 方法 > 服务选项 > 服务注释 @timeout > 插件参数 timeout
+func (p *methodWithComment) GetTimeout() time.Duration {
+	if d := p.getMethodTimeout(); d > 0 {
+		return d
+	}
+	if p.SvcRoute != nil {
+		if d, _ := parseTimeout(p.SvcRoute.Timeout); d > 0 {
+			return d
+		}
+	}
+	if d, _ := parseTimeoutTag(p.SvcCommentList); d > 0 {
+		return d
+	}
+	return p.DefTimeout
+}
+
+// 生成代码中的超时表达式, 无超时返回空
+// 流式方法只使用方法级超时, 服务级/全局默认值只作用于单次调用
+func (p *methodWithComment) GetTimeoutExpr() string {
+	d := p.GetTimeout()
+	if p.GetServerStreaming() || p.GetClientStreaming() {
+		d = p.getMethodTimeout()
+	}
+	switch {
+	case d <= 0:
+		return ""
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d*time.Second", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%d*time.Millisecond", d/time.Millisecond)
+	case d%time.Microsecond == 0:
+		return fmt.Sprintf("%d*time.Microsecond", d/time.Microsecond)
+	}
+	return fmt.Sprintf("%d*time.Nanosecond", d)
+}
+
+// 收集文件中的所有服务及方法(含注释/选项), Go及其他语言的输出共用
+func collectServices(p *param, name2Path map[string]string, path2Comment map[string]string) []*serviceWithComment {
 	getComment := func(keys ...string) string {
 		comment := ""
 		pt := strings.Join(keys, "/")
@@ -266,9 +483,7 @@ func applyTemplate(p param, name2Path map[string]string, path2Comment map[string
 		msgName := generator.CamelCase(*msg.Name)
 		msg.Name = &msgName
 	}
-	var addiImport []string
-	// 需要输出的服务
-	var outServices []*serviceWithComment
+	var services []*serviceWithComment
 	for _, svc := range p.Services {
 		//
 		svcName := generator.CamelCase(*svc.Name)
@@ -279,27 +494,90 @@ func applyTemplate(p param, name2Path map[string]string, path2Comment map[string
 			Comment: getComment(*p.Name, *svc.Name),
 		}
 		svcIt.ParseComment()
-		for _, im := range svcIt.ParseAdditionalImport() {
-			if !slice.ContainsString(addiImport, im) {
-				addiImport = append(addiImport, im)
-			}
-		}
+		svcRoute := getServiceRoute(svc)
 		for _, meth := range svc.Methods {
 			methName := generator.CamelCase(*meth.Name)
 			meth.Name = &methName
 			mIt := &methodWithComment{
-				Method:  meth,
-				Comment: getComment(*p.Name, *svc.Name, *meth.Name),
+				Method:         meth,
+				Comment:        getComment(*p.Name, *svc.Name, *meth.Name),
+				Route:          getMethodRoute(meth),
+				SvcRoute:       svcRoute,
+				SvcCommentList: svcIt.CommentList,
+				DefTimeout:     p.DefaultTimeout,
+				DefAuth:        p.DefaultAuth,
 			}
 			mIt.ParseComment()
 			svcIt.MethodsWithComment = append(svcIt.MethodsWithComment, mIt)
 		}
+		services = append(services, svcIt)
+	}
+	return services
+}
 
+func applyTemplate(p param, name2Path map[string]string, path2Comment map[string]string) (string, error) {
+	out := bytes.NewBuffer(nil)
+	var addiImport []string
+	imported := map[string]bool{}
+	for _, pkg := range p.Imports {
+		imported[pkg.Path] = true
+	}
+	// 需要输出的服务
+	var outServices []*serviceWithComment
+	for _, svcIt := range collectServices(&p, name2Path, path2Comment) {
+		for _, im := range svcIt.ParseAdditionalImport() {
+			if !imported[im] && !slice.ContainsString(addiImport, im) {
+				addiImport = append(addiImport, im)
+			}
+		}
 		if svcIt.CanOutput() {
 			outServices = append(outServices, svcIt)
 		}
 	}
 
+	withClientStream := false
+	withSnappy := false
+	for _, svc := range outServices {
+		for _, m := range svc.MethodsWithComment {
+			if !m.CanOutput() {
+				continue
+			}
+			if m.IsClientStream() || m.IsBidiStream() {
+				withClientStream = true
+			}
+			if c := m.GetCompress(); c != nil && c.Flag == compressFlags["snappy"] {
+				withSnappy = true
+			}
+		}
+	}
+	// 只有用到snappy时才引入第三方依赖
+	if withSnappy && !imported[snappyImport] && !slice.ContainsString(addiImport, snappyImport) {
+		addiImport = append(addiImport, snappyImport)
+	}
+
+	// 客户端只支持单次调用的方法, 方法名不能重复或与客户端自身的方法冲突
+	var clientMethods []*methodWithComment
+	if p.GoClient {
+		clientNames := map[string]bool{"HandlePush": true, "Done": true, "Err": true, "Close": true, "Invoke": true}
+		for _, svc := range outServices {
+			for _, m := range svc.MethodsWithComment {
+				if !m.CanOutput() || m.GetServerStreaming() || m.GetClientStreaming() {
+					continue
+				}
+				if clientNames[m.GetName()] {
+					return "", fmt.Errorf("go_client: method name %s.%s conflicts with another client method", svc.GetName(), m.GetName())
+				}
+				clientNames[m.GetName()] = true
+				clientMethods = append(clientMethods, m)
+			}
+		}
+		for _, im := range []string{"encoding/binary", "net"} {
+			if !imported[im] && !slice.ContainsString(addiImport, im) {
+				addiImport = append(addiImport, im)
+			}
+		}
+	}
+
 	p.AdditionImports = addiImport
 	if err := headerTemplate.Execute(out, p); err != nil {
 		return "", err
@@ -328,15 +606,26 @@ func applyTemplate(p param, name2Path map[string]string, path2Comment map[string
 		}
 	}
 
+	// 按目的服务名排序, 保证每次生成的代码一致(多个方法共用同一协议时, structName2id取最后登记的id)
 	var tarServices []*serviceWithComment
 	for _, svc := range tmpServices {
 		tarServices = append(tarServices, svc)
 	}
+	sort.Slice(tarServices, func(i, j int) bool {
+		return tarServices[i].TargetName < tarServices[j].TargetName
+	})
 
 	def := defParam{
 		File:                p.File,
 		ServicesWithComment: tarServices,
 		DefinePrefix:        p.DefinePrefix,
+		WithClientStream:    withClientStream,
+		Declares:            p.Declares,
+		Pushes:              p.Pushes,
+		ClientMethods:       clientMethods,
+		ErrorReply:          p.ErrorReply,
+		Json:                p.Json,
+		WithSnappy:          withSnappy,
 	}
 	if err := defTemplate.Execute(out, def); err != nil {
 		return "", err
@@ -346,6 +635,12 @@ func applyTemplate(p param, name2Path map[string]string, path2Comment map[string
 		return "", err
 	}
 
+	if p.GoClient {
+		if err := clientTemplate.Execute(out, def); err != nil {
+			return "", err
+		}
+	}
+
 	return out.String(), nil
 }
 
@@ -388,10 +683,101 @@ type {{$prefix}}TransmitArgs struct {
 	Data        []byte
 	Codec       uint16
 	Opts        []grpc.DialOption
-	DoneCallback func(proto.Message)
+	DoneCallback func(proto.Message) // 单次调用的响应, 或流式调用的每一条响应
+	StreamEndCallback func() // 流式调用正常结束(io.EOF)时回调, 可为空
+	ErrorCallback func(error) // 异步转发(AsyncTransmitor)或客户端流/双向流打开后失败时回调, 可为空
+	SessionId   uint64 // 客户端会话id, 客户端流/双向流按 SessionId+Method 绑定
+	Ctx         context.Context // 调用方的context, 为空则使用context.Background(); 会话断开或服务关闭时取消, 可中止进行中的后端调用
 	ctx         context.Context
+	stat        *{{$prefix}}callStat // 设置了MetricsSink时记录本次调用
+	{{- if .WithClientStream}}
+	stream      *{{$prefix}}streamHandle // 本次调用打开的客户端流/双向流
+	{{- end}}
+}
+{{if .WithClientStream}}
+// 客户端流/双向流句柄, 与会话及@upid绑定
+// 首个数据包登记句柄后在后台打开后端流, 打开完成(或失败)前其他数据包在Send中等待
+type {{$prefix}}streamHandle struct {
+	lock      sync.Mutex
+	once      sync.Once
+	readyOnce sync.Once
+	ready     chan struct{} // 后端流已打开或打开失败
+	err       error         // 打开失败的原因
+	closed    chan struct{}
+	send      func(data []byte, codec uint16) error
+	closeSend func() error
+}
+
+type {{$prefix}}streamKey struct {
+	session uint64
+	method  string
+}
+
+func {{$prefix}}newStreamHandle() *{{$prefix}}streamHandle {
+	return &{{$prefix}}streamHandle{
+		ready:  make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// 后端流已打开且首个数据包已发送
+func (p *{{$prefix}}streamHandle) open(send func([]byte, uint16) error, closeSend func() error) {
+	p.lock.Lock()
+	p.send = send
+	p.closeSend = closeSend
+	select {
+	case <-p.closed:
+		// 打开期间已被关闭
+		if closeSend != nil {
+			closeSend()
+		}
+	default:
+	}
+	p.lock.Unlock()
+	p.setReady(nil)
+}
+
+// 结束打开流程, 返回是否由本次调用结束
+func (p *{{$prefix}}streamHandle) setReady(err error) bool {
+	done := false
+	p.readyOnce.Do(func() {
+		p.err = err
+		close(p.ready)
+		done = true
+	})
+	return done
+}
+
+func (p *{{$prefix}}streamHandle) Send(data []byte, codec uint16) error {
+	<-p.ready
+	if p.err != nil {
+		return p.err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.closed:
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeStream, "stream closed")
+	default:
+	}
+	if p.send == nil {
+		// 拦截器未调用next, 流没有打开
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeStream, "stream closed")
+	}
+	return p.send(data, codec)
 }
 
+func (p *{{$prefix}}streamHandle) Close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.once.Do(func() {
+		close(p.closed)
+		if p.closeSend != nil {
+			p.closeSend()
+		}
+	})
+}
+{{end}}
 var (
 	// definePrefix = {{.DefinePrefix}}
 	// tag @id to package.TargetService/Method map
@@ -403,11 +789,48 @@ var (
 
 	{{$prefix}}structName2id = map[string]uint16{}
 
+	// package.TargetService/Method => 路由信息
+	{{$prefix}}meth2route = map[string]{{$prefix}}RouteInfo{}
+
+	{{$prefix}}interceptorLock sync.RWMutex
+
+	{{$prefix}}interceptors []{{$prefix}}TransmitInterceptor
+
+	{{$prefix}}metricsLock sync.RWMutex
+
+	{{$prefix}}metricsSink {{$prefix}}MetricsSink
+
+	{{$prefix}}tracerLock sync.RWMutex
+
+	{{$prefix}}tracer {{$prefix}}Tracer
+
+	// 推送协议(@push)的id
+	{{$prefix}}pushIds = map[uint16]bool{ {{- range $d := .Pushes}}{{$d.Id}}: true, {{end -}} }
+
+	// package.TargetService/Method => 限流配置(@ratelimit)
+	{{$prefix}}meth2ratelimit = map[string]{{$prefix}}RateLimit{}
+
+	{{$prefix}}limiterLock sync.RWMutex
+
+	{{$prefix}}limiterStore {{$prefix}}LimiterStore = {{$prefix}}NewMemoryLimiterStore()
+
+	{{$prefix}}checkerLock sync.RWMutex
+
+	{{$prefix}}sessionChecker {{$prefix}}SessionChecker
+
+	// package.TargetService/Method => 响应压缩配置(@compress)
+	{{$prefix}}meth2compress = map[string]{{$prefix}}compressConf{}
+
 	{{range $svc := .ServicesWithComment}}
 	{{$prefix}}transmit_{{$svc.TargetName}}_Map = map[string]{{$prefix}}transmit_{{$svc.TargetName}}_Handler{}
 	{{end}}
 
 	{{$prefix}}serviceMap = map[string]{{$prefix}}registerHandler{}
+	{{if .WithClientStream}}
+	{{$prefix}}streamLock sync.Mutex
+
+	{{$prefix}}streamMap = map[{{$prefix}}streamKey]*{{$prefix}}streamHandle{}
+	{{end}}
 )
 
 func init() {
@@ -420,9 +843,37 @@ func init() {
 	for k, v := range {{$prefix}}id2meth {
 		{{$prefix}}meth2id[v] = k
 	}
+	// meth2route
+	{{range $svc := .ServicesWithComment}}
+		{{range $m := $svc.MethodsWithComment}}
+	{{$prefix}}meth2route["{{$.GoPkg.Name}}.{{$svc.TargetName}}/{{$m.GetName}}"] = {{$prefix}}RouteInfo{
+		Method:       "{{$.GoPkg.Name}}.{{$svc.TargetName}}/{{$m.GetName}}",
+		UpId:         {{$m.GetUpId}},
+		DownId:       {{$m.GetDownId}},
+		Request:      "{{$m.RequestType.GetName}}",
+		Response:     "{{$m.ResponseType.GetName}}",
+		ClientStream: {{$m.GetClientStreaming}},
+		ServerStream: {{$m.GetServerStreaming}},
+		{{- with $m.GetAuth}}
+		Auth:         {{$prefix}}AuthPolicy{Level: {{$prefix}}Auth{{.Level}}{{if .Role}}, Role: "{{.Role}}"{{end}}},
+		{{- end}}
+	}{{end}}
+	{{end}}
+	// meth2ratelimit
+	{{range $svc := .ServicesWithComment}}
+		{{range $m := $svc.MethodsWithComment}}
+{{with $m.GetRateLimit}}{{$prefix}}meth2ratelimit["{{$.GoPkg.Name}}.{{$svc.TargetName}}/{{$m.GetName}}"] = {{$prefix}}RateLimit{Rate: {{.RateExpr}}, Burst: {{.Burst}}, By: "{{.By}}"}{{end}}{{end}}
+	{{end}}
+	// meth2compress
+	{{range $svc := .ServicesWithComment}}
+		{{range $m := $svc.MethodsWithComment}}
+{{with $m.GetCompress}}{{$prefix}}meth2compress["{{$.GoPkg.Name}}.{{$svc.TargetName}}/{{$m.GetName}}"] = {{$prefix}}compressConf{threshold: {{.Threshold}}, flag: {{$prefix}}{{.Flag}}}{{end}}{{end}}
+	{{end}}
 	// id2struct
-	{{$prefix}}id2struct[6172] = func()proto.Message{return &imdef.ImError{}} // todo 这行为工具写死的代码,应该改成模板
-	{{$prefix}}id2struct[8197] = func()proto.Message{return &comm.HfError{}}
+	{{range $d := .Declares}}
+	{{$prefix}}id2struct[{{$d.Id}}] = func()proto.Message{return &{{$d.GetGoType}}{}}{{end}}
+	{{range $d := .Pushes}}
+	{{$prefix}}id2struct[{{$d.Id}}] = func()proto.Message{return &{{$d.GetGoType}}{}}{{end}}
 	{{range $svr := .ServicesWithComment}}
 		{{range $m := $svr.MethodsWithComment}}
 			{{$id := $m.GetUpId}}{{if ne $id 0}}{{$prefix}}id2struct[{{$id}}] = func()proto.Message{return &{{$m.GetRequestPackage}}{{$m.RequestType.GetName}}{}}{{end}}
@@ -431,8 +882,10 @@ func init() {
 	{{end}}
 	
 	// structName2id
-	{{$prefix}}structName2id["ImError"] = 6172 // todo 这行为工具写死的代码,应该改成模板
-	{{$prefix}}structName2id["HfError"] = 8197
+	{{range $d := .Declares}}
+	{{$prefix}}structName2id["{{$d.GetName}}"] = {{$d.Id}}{{end}}
+	{{range $d := .Pushes}}
+	{{$prefix}}structName2id["{{$d.GetName}}"] = {{$d.Id}}{{end}}
 	{{range $svr := .ServicesWithComment}}
 		{{range $m := $svr.MethodsWithComment}}
 			{{$id := $m.GetUpId}}{{if ne $id 0}}{{$prefix}}structName2id["{{$m.RequestType.GetName}}"] = {{$id}}{{end}}
@@ -450,22 +903,185 @@ func init() {
 	{{$prefix}}serviceMap["{{$.GoPkg.Name}}.{{$svc.TargetName}}"] = {{$prefix}}register_{{$svc.TargetName}}_Transmitor{{end}}
 }
 
+// 包头Codec: 低8位为编码格式, 高8位为压缩标记
+const (
+	{{$prefix}}CodecProto uint16 = 0 // 编码格式
+	{{$prefix}}CodecJson  uint16 = 1
+
+	{{$prefix}}CodecFormatMask uint16 = 0x00ff
+	{{$prefix}}CodecFlagGzip   uint16 = 0x0100 // 压缩标记
+	{{$prefix}}CodecFlagSnappy uint16 = 0x0200
+
+	{{$prefix}}codecCompressMask = {{$prefix}}CodecFlagGzip | {{$prefix}}CodecFlagSnappy
+)
+
+// 解压后允许的最大长度, 防止压缩炸弹
+var {{$prefix}}MaxDecompressSize = 4 << 20
+
+type {{$prefix}}compressConf struct {
+	threshold int
+	flag      uint16
+}
+
+func {{$prefix}}compressBytes(data []byte, codec uint16) ([]byte, error) {
+	switch codec & {{$prefix}}codecCompressMask {
+	case 0:
+		return data, nil
+	case {{$prefix}}CodecFlagGzip:
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+{{- if .WithSnappy}}
+	case {{$prefix}}CodecFlagSnappy:
+		return snappy.Encode(nil, data), nil
+{{- end}}
+	}
+	return nil, fmt.Errorf("compress flag error: %#x", codec)
+}
+
+func {{$prefix}}decompressBytes(data []byte, codec uint16) ([]byte, error) {
+	switch codec & {{$prefix}}codecCompressMask {
+	case 0:
+		return data, nil
+	case {{$prefix}}CodecFlagGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		buf := &bytes.Buffer{}
+		if _, err := buf.ReadFrom(io.LimitReader(r, int64({{$prefix}}MaxDecompressSize)+1)); err != nil {
+			return nil, err
+		}
+		if buf.Len() > {{$prefix}}MaxDecompressSize {
+			return nil, fmt.Errorf("decompressed size exceeds %d", {{$prefix}}MaxDecompressSize)
+		}
+		return buf.Bytes(), nil
+{{- if .WithSnappy}}
+	case {{$prefix}}CodecFlagSnappy:
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > {{$prefix}}MaxDecompressSize {
+			return nil, fmt.Errorf("decompressed size exceeds %d", {{$prefix}}MaxDecompressSize)
+		}
+		return snappy.Decode(nil, data)
+{{- end}}
+	}
+	return nil, fmt.Errorf("compress flag error: %#x", codec)
+}
+
+// 编解码器, 可通过RegisterCodec注册新的codec
+type {{$prefix}}Codec interface {
+	Marshal(inst proto.Message) ([]byte, error)
+	Unmarshal(data []byte, inst proto.Message) error
+}
+
+type {{$prefix}}protoCodec struct{}
+
+func ({{$prefix}}protoCodec) Marshal(inst proto.Message) ([]byte, error) {
+	return proto.Marshal(inst)
+}
+
+func ({{$prefix}}protoCodec) Unmarshal(data []byte, inst proto.Message) error {
+	return proto.Unmarshal(data, inst)
+}
+
+// protobuf标准json格式, 正确处理oneof/枚举/int64/Timestamp等
+type {{$prefix}}JsonCodec struct {
+	Marshaler   jsonpb.Marshaler
+	Unmarshaler jsonpb.Unmarshaler
+}
+
+func (p *{{$prefix}}JsonCodec) Marshal(inst proto.Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := p.Marshaler.Marshal(buf, inst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *{{$prefix}}JsonCodec) Unmarshal(data []byte, inst proto.Message) error {
+	return p.Unmarshaler.Unmarshal(bytes.NewReader(data), inst)
+}
+
+var (
+	{{$prefix}}codecLock sync.RWMutex
+	{{$prefix}}codecMap  = map[uint16]{{$prefix}}Codec{
+		{{$prefix}}CodecProto: {{$prefix}}protoCodec{},
+		{{$prefix}}CodecJson: &{{$prefix}}JsonCodec{
+			Marshaler: jsonpb.Marshaler{
+				OrigName:     {{.Json.OrigName}},
+				EmitDefaults: {{.Json.EmitDefaults}},
+				EnumsAsInts:  {{.Json.EnumsAsInts}},
+			},
+			Unmarshaler: jsonpb.Unmarshaler{AllowUnknownFields: true},
+		},
+	}
+)
+
+// 注册或替换codec, codec为编码格式(低8位), 应在开始转发前调用
+func {{$prefix}}RegisterCodec(codec uint16, c {{$prefix}}Codec) {
+	{{$prefix}}codecLock.Lock()
+	defer {{$prefix}}codecLock.Unlock()
+	{{$prefix}}codecMap[codec&{{$prefix}}CodecFormatMask] = c
+}
+
+func {{$prefix}}GetCodec(codec uint16) ({{$prefix}}Codec, bool) {
+	{{$prefix}}codecLock.RLock()
+	defer {{$prefix}}codecLock.RUnlock()
+	c, ok := {{$prefix}}codecMap[codec&{{$prefix}}CodecFormatMask]
+	return c, ok
+}
+
 func {{$prefix}}DecodeBytes(data []byte, codec uint16, inst proto.Message) error {
-	if codec == 0 {
-		return proto.Unmarshal(data, inst)
-	} else if codec == 1 {
-		return json.Unmarshal(data, inst)
+	c, ok := {{$prefix}}GetCodec(codec)
+	if !ok {
+		return fmt.Errorf("codec type error: %d", codec)
+	}
+	data, err := {{$prefix}}decompressBytes(data, codec)
+	if err != nil {
+		return err
 	}
-	return errors.New("codec type error")
+	return c.Unmarshal(data, inst)
 }
 
 func {{$prefix}}EncodeBytes(codec uint16, inst proto.Message) ([]byte, error) {
-	if codec == 0 {
-		return proto.Marshal(inst)
-	} else if codec == 1 {
-		return json.Marshal(inst)
+	c, ok := {{$prefix}}GetCodec(codec)
+	if !ok {
+		return nil, fmt.Errorf("codec type error: %d", codec)
+	}
+	data, err := c.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+	return {{$prefix}}compressBytes(data, codec)
+}
+
+// 编码方法meth(package.TargetService/Method)的响应, 返回数据及包头应使用的codec
+// 方法设置了@compress且编码后超过阈值时压缩; codec已带压缩标记时按该标记压缩
+func {{$prefix}}EncodeReply(meth string, codec uint16, inst proto.Message) ([]byte, uint16, error) {
+	data, err := {{$prefix}}EncodeBytes(codec, inst)
+	if err != nil {
+		return nil, codec, err
 	}
-	return nil, errors.New("codec type error")
+	conf, ok := {{$prefix}}meth2compress[meth]
+	if !ok || codec&{{$prefix}}codecCompressMask != 0 || len(data) <= conf.threshold {
+		return data, codec, nil
+	}
+	codec |= conf.flag
+	data, err = {{$prefix}}compressBytes(data, codec)
+	if err != nil {
+		return nil, codec, err
+	}
+	return data, codec, nil
 }
 
 // get meth(package.TargetService/Method) by id(cmdid)
@@ -485,11 +1101,25 @@ func {{$prefix}}GetMsgObjById(id uint16) (proto.Message, bool) {
 	return nil, false
 }
 
+// 根据协议对象获取对应的id, 未绑定id返回0
 func {{$prefix}}GetIdByMsgObj(obj proto.Message) uint16 {
 	name := comm_libs.GetStructName(obj)
 	return {{$prefix}}structName2id[name]
 }
 
+// 编码服务端推送(@push)或声明(@declare)的协议, 返回包头使用的id及body
+func {{$prefix}}EncodePush(msg proto.Message, codec uint16) (uint16, []byte, error) {
+	id := {{$prefix}}GetIdByMsgObj(msg)
+	if id == 0 {
+		return 0, nil, {{$prefix}}newTransmitError({{$prefix}}ErrCodeArgs, "message has no id: "+comm_libs.GetStructName(msg))
+	}
+	body, err := {{$prefix}}EncodeBytes(codec, msg)
+	if err != nil {
+		return 0, nil, {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
+	}
+	return id, body, nil
+}
+
 func {{$prefix}}ParseMethod(method string) (string, string, string, error) {
 	method = strings.Trim(method, "/")
 	dotIdx := strings.Index(method, ".")
@@ -503,76 +1133,1254 @@ func {{$prefix}}ParseMethod(method string) (string, string, string, error) {
 	return packageName, serviceName, methodName, nil
 }
 
-// define call enter point
-func {{$prefix}}RegisterTransmitor(args *{{$prefix}}TransmitArgs) error {
-	if len(args.Method) < 1 || len(args.Endpoint) < 1 || len(args.MD) < 1 || args.DoneCallback == nil {
-		return errors.New("transmit args empty")
-	}
+// 转发错误码: 后端错误使用grpc状态码(codes.Code, 0~16), 网关自身的错误从1000开始
+const (
+	{{$prefix}}ErrCodeArgs      uint32 = 1000 + iota // 转发参数错误
+	{{$prefix}}ErrCodeCodec                          // 编解码错误
+	{{$prefix}}ErrCodeMethod                         // 未注册的方法
+	{{$prefix}}ErrCodeDial                           // 连接后端失败
+	{{$prefix}}ErrCodeQueueFull                      // 异步队列已满
+	{{$prefix}}ErrCodeClosed                         // 异步转发器已关闭
+	{{$prefix}}ErrCodeStream                         // 客户端流已关闭
+	{{$prefix}}ErrCodeRateLimit                      // 超出限流(@ratelimit)
+	{{$prefix}}ErrCodeAuth                           // 鉴权失败(@auth)
+)
 
-	packageName, serviceName, _, err := {{$prefix}}ParseMethod(args.Method)
-	if err != nil {
-		return err
-	}
-	packageService := packageName + "." + serviceName
-	if handler, ok := {{$prefix}}serviceMap[packageService]; ok {
-		err := handler(args)
-		return err
-	}
-	return errors.New("method not register yet")
+// 转发错误, RegisterTransmitor/ErrorCallback返回的错误均为该类型
+type {{$prefix}}TransmitError struct {
+	Code    uint32
+	Message string
+	Status  *status.Status // 后端返回的grpc状态(含details), 网关错误为nil
 }
 
-`))
+func (p *{{$prefix}}TransmitError) Error() string {
+	return fmt.Sprintf("transmit err[code:%d msg:%s]", p.Code, p.Message)
+}
 
-	transTamplate = template.Must(template.New("meth").Parse(`
-// registor single service enter point
-{{$prefix := .DefinePrefix}}
-{{range $svc := .ServicesWithComment}}
-// *********************************************************************************
-// 注册{{$svc.GetName}}传输转换入口
-{{if $svc.Comment}}{{$svc.GetFormatComment}}{{end}}
-func {{$prefix}}register_{{$svc.TargetName}}_Transmitor(args *{{$prefix}}TransmitArgs) (err error) {
-	if args.Conn == nil {
-		conn, err := grpc.Dial(args.Endpoint, args.Opts...)
-		if err != nil {
-			return err
-		}
-		defer conn.Close()
-		args.Conn = conn
+func {{$prefix}}newTransmitError(code uint32, msg string) *{{$prefix}}TransmitError {
+	return &{{$prefix}}TransmitError{Code: code, Message: msg}
+}
+
+// 转换为TransmitError, 后端错误保留grpc状态的code/message/details
+func {{$prefix}}ToTransmitError(err error) *{{$prefix}}TransmitError {
+	if err == nil {
+		return nil
 	}
-	
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 5*time.Second)
-	ctx = metadata.NewOutgoingContext(ctx, args.MD)
-	args.ctx = ctx
-	//
-	client := {{$svc.TargetPkg}}New{{$svc.TargetName}}Client(args.Conn)
-	handler, ok := {{$prefix}}transmit_{{$svc.TargetName}}_Map[args.Method]
-	if !ok {
-		return errors.New("method error")
+	if te, ok := err.(*{{$prefix}}TransmitError); ok {
+		return te
 	}
-	res, err := handler(args, client)
-	if err != nil {
-		return err
+	st := status.Convert(err)
+	return &{{$prefix}}TransmitError{Code: uint32(st.Code()), Message: st.Message(), Status: st}
+}
+{{with .ErrorReply}}
+// 将错误转换为错误响应协议({{.GetGoType}}), 下行id为 {{.Id}}
+func {{$prefix}}NewErrorReply(err error) proto.Message {
+	te := {{$prefix}}ToTransmitError(err)
+	reply := &{{.GetGoType}}{
+		{{.CodeField}}: {{.CodeType}}(te.Code),
+		{{- if .MsgField}}
+		{{.MsgField}}: te.Message,
+		{{- end}}
 	}
-	args.DoneCallback(res)
-	return nil
+	{{- if .DetailsField}}
+	if te.Status != nil {
+		reply.{{.DetailsField}} = te.Status.Proto().Details
+	}
+	{{- end}}
+	return reply
 }
-
-{{range $m := $svc.MethodsWithComment}}
+{{end}}
+func {{$prefix}}checkTransmitArgs(args *{{$prefix}}TransmitArgs) error {
+	if len(args.Method) < 1 || len(args.Endpoint) < 1 || len(args.MD) < 1 || args.DoneCallback == nil {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeArgs, "transmit args empty")
+	}
+	return nil
+}
+
+// define call enter point
+// 转发方法的路由信息
+type {{$prefix}}RouteInfo struct {
+	Method       string // package.TargetService/Method
+	UpId         uint16 // @upid
+	DownId       uint16 // @downid, 未设置为0
+	Request      string // 请求协议名
+	Response     string // 响应协议名
+	ClientStream bool
+	ServerStream bool
+	Auth         {{$prefix}}AuthPolicy // 鉴权要求(@auth)
+}
+
+func {{$prefix}}GetRouteInfo(meth string) ({{$prefix}}RouteInfo, bool) {
+	info, ok := {{$prefix}}meth2route[meth]
+	return info, ok
+}
+
+// 调用后端方法, ctx为带有args.MD的outgoing context
+type {{$prefix}}TransmitHandler func(ctx context.Context, args *{{$prefix}}TransmitArgs) (proto.Message, error)
+
+// 转发拦截器, 包裹每一次后端方法调用, 调用next继续执行, 不调用则中止转发
+// 可修改args.Data, 或通过metadata.AppendToOutgoingContext修改ctx传给next
+// 客户端流/双向流的每个数据包都经过拦截器, 打开流之后的数据包next只负责发送, 返回nil
+type {{$prefix}}TransmitInterceptor func(ctx context.Context, args *{{$prefix}}TransmitArgs, info {{$prefix}}RouteInfo, next {{$prefix}}TransmitHandler) (proto.Message, error)
+
+// 追加拦截器, 按添加顺序由外到内执行, 应在开始转发前调用
+func {{$prefix}}UseInterceptor(list ...{{$prefix}}TransmitInterceptor) {
+	{{$prefix}}interceptorLock.Lock()
+	defer {{$prefix}}interceptorLock.Unlock()
+	{{$prefix}}interceptors = append({{$prefix}}interceptors, list...)
+}
+
+// 用拦截器链包裹handler
+func {{$prefix}}chainInterceptors(info {{$prefix}}RouteInfo, handler {{$prefix}}TransmitHandler) {{$prefix}}TransmitHandler {
+	{{$prefix}}interceptorLock.RLock()
+	list := {{$prefix}}interceptors
+	{{$prefix}}interceptorLock.RUnlock()
+	for i := len(list) - 1; i >= 0; i-- {
+		interceptor, next := list[i], handler
+		handler = func(ctx context.Context, args *{{$prefix}}TransmitArgs) (proto.Message, error) {
+			return interceptor(ctx, args, info, next)
+		}
+	}
+	return handler
+}
+
+// 一次转发调用的观测数据
+type {{$prefix}}CallMetrics struct {
+	Route      {{$prefix}}RouteInfo // 路由标签: UpId, Method等
+	Code       uint32        // 结果状态, 0成功, 否则为TransmitError.Code
+	DecodeTime time.Duration // 请求解码耗时, 客户端流为所有数据包的累计
+	CallTime   time.Duration // 后端调用耗时(不含解码), 流式方法为整个流的时长
+	ReqCount   int           // 请求数据包数量
+	ReqSize    int           // 请求数据长度(编码后)
+	RespCount  int           // 响应数量
+	RespSize   int           // 响应长度(protobuf)
+}
+
+// 指标接收器, 每次转发结束时调用, 需并发安全; 未设置时不做任何统计
+type {{$prefix}}MetricsSink interface {
+	ObserveCall(m *{{$prefix}}CallMetrics)
+}
+
+// 设置指标接收器, nil表示关闭统计, 应在开始转发前调用
+func {{$prefix}}SetMetricsSink(sink {{$prefix}}MetricsSink) {
+	{{$prefix}}metricsLock.Lock()
+	defer {{$prefix}}metricsLock.Unlock()
+	{{$prefix}}metricsSink = sink
+}
+
+func {{$prefix}}getMetricsSink() {{$prefix}}MetricsSink {
+	{{$prefix}}metricsLock.RLock()
+	defer {{$prefix}}metricsLock.RUnlock()
+	return {{$prefix}}metricsSink
+}
+
+// 单次调用的统计, 客户端流的后续数据包会在其他goroutine中累加
+type {{$prefix}}callStat struct {
+	lock  sync.Mutex
+	start time.Time
+	m     {{$prefix}}CallMetrics
+}
+
+func (p *{{$prefix}}callStat) addRequest(size int, decode time.Duration) {
+	p.lock.Lock()
+	p.m.ReqCount++
+	p.m.ReqSize += size
+	p.m.DecodeTime += decode
+	p.lock.Unlock()
+}
+
+func (p *{{$prefix}}callStat) addReply(reply proto.Message) {
+	size := proto.Size(reply)
+	p.lock.Lock()
+	p.m.RespCount++
+	p.m.RespSize += size
+	p.lock.Unlock()
+}
+
+func (p *{{$prefix}}callStat) finish(sink {{$prefix}}MetricsSink, err error) {
+	p.lock.Lock()
+	m := p.m
+	p.lock.Unlock()
+	m.CallTime = time.Since(p.start) - m.DecodeTime
+	if err != nil {
+		m.Code = {{$prefix}}ToTransmitError(err).Code
+	}
+	sink.ObserveCall(&m)
+}
+
+// 解码请求数据, 设置了MetricsSink时记录耗时及长度
+func {{$prefix}}decodeRequest(args *{{$prefix}}TransmitArgs, data []byte, codec uint16, inst proto.Message) error {
+	if args.stat == nil {
+		return {{$prefix}}DecodeBytes(data, codec, inst)
+	}
+	start := time.Now()
+	err := {{$prefix}}DecodeBytes(data, codec, inst)
+	args.stat.addRequest(len(data), time.Since(start))
+	return err
+}
+
+func {{$prefix}}doneCallback(args *{{$prefix}}TransmitArgs, reply proto.Message) {
+	if args.stat != nil {
+		args.stat.addReply(reply)
+	}
+	args.DoneCallback(reply)
+}
+
+// 路由统计
+type {{$prefix}}RouteStats struct {
+	UpId        uint16
+	Method      string
+	Count       int64            // 调用次数
+	Errors      int64            // 失败次数
+	Codes       map[uint32]int64 // 结果状态 => 次数
+	DecodeTime  time.Duration    // 累计解码耗时
+	CallTime    time.Duration    // 累计后端调用耗时
+	MaxCallTime time.Duration
+	ReqSize     int64 // 累计请求长度
+	RespSize    int64 // 累计响应长度
+}
+
+// 内存指标, 按方法汇总, 可用于测试或自行导出
+type {{$prefix}}MemoryMetrics struct {
+	lock   sync.Mutex
+	routes map[string]*{{$prefix}}RouteStats
+}
+
+func {{$prefix}}NewMemoryMetrics() *{{$prefix}}MemoryMetrics {
+	return &{{$prefix}}MemoryMetrics{
+		routes: map[string]*{{$prefix}}RouteStats{},
+	}
+}
+
+func (p *{{$prefix}}MemoryMetrics) ObserveCall(m *{{$prefix}}CallMetrics) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	st, ok := p.routes[m.Route.Method]
+	if !ok {
+		st = &{{$prefix}}RouteStats{UpId: m.Route.UpId, Method: m.Route.Method, Codes: map[uint32]int64{}}
+		p.routes[m.Route.Method] = st
+	}
+	st.Count++
+	if m.Code != 0 {
+		st.Errors++
+	}
+	st.Codes[m.Code]++
+	st.DecodeTime += m.DecodeTime
+	st.CallTime += m.CallTime
+	if m.CallTime > st.MaxCallTime {
+		st.MaxCallTime = m.CallTime
+	}
+	st.ReqSize += int64(m.ReqSize)
+	st.RespSize += int64(m.RespSize)
+}
+
+// 返回各方法(package.TargetService/Method)统计的副本
+func (p *{{$prefix}}MemoryMetrics) Snapshot() map[string]{{$prefix}}RouteStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	out := make(map[string]{{$prefix}}RouteStats, len(p.routes))
+	for k, st := range p.routes {
+		it := *st
+		it.Codes = make(map[uint32]int64, len(st.Codes))
+		for code, n := range st.Codes {
+			it.Codes[code] = n
+		}
+		out[k] = it
+	}
+	return out
+}
+
+func (p *{{$prefix}}MemoryMetrics) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.routes = map[string]*{{$prefix}}RouteStats{}
+}
+
+// 注入到后端metadata的追踪头
+const (
+	{{$prefix}}TraceIdKey   = "x-trace-id"   // 客户端在TransmitArgs.MD中传入时沿用, 否则由网关生成
+	{{$prefix}}RequestIdKey = "x-request-id" // 每次转发调用生成
+)
+
+// 追踪span, 转发结束时调用Finish
+type {{$prefix}}Span interface {
+	Finish(err error)
+}
+
+// 追踪器, 为每次转发调用创建span; 返回的ctx用于后端调用,
+// 可通过metadata.AppendToOutgoingContext注入自己的追踪头(如traceparent)
+type {{$prefix}}Tracer interface {
+	StartSpan(ctx context.Context, info {{$prefix}}RouteInfo, traceId, requestId string) (context.Context, {{$prefix}}Span)
+}
+
+// 设置追踪器, nil表示不创建span(仍会注入trace id及request id), 应在开始转发前调用
+func {{$prefix}}SetTracer(t {{$prefix}}Tracer) {
+	{{$prefix}}tracerLock.Lock()
+	defer {{$prefix}}tracerLock.Unlock()
+	{{$prefix}}tracer = t
+}
+
+func {{$prefix}}getTracer() {{$prefix}}Tracer {
+	{{$prefix}}tracerLock.RLock()
+	defer {{$prefix}}tracerLock.RUnlock()
+	return {{$prefix}}tracer
+}
+
+func {{$prefix}}newTraceId(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// 复制md并注入trace id及request id, 不修改调用方的md
+func {{$prefix}}traceMD(md metadata.MD) (out metadata.MD, traceId, requestId string) {
+	out = md.Copy()
+	if out == nil {
+		out = metadata.MD{}
+	}
+	if list := out.Get({{$prefix}}TraceIdKey); len(list) > 0 && len(list[0]) > 0 {
+		traceId = list[0]
+	} else {
+		traceId = {{$prefix}}newTraceId(16)
+		out.Set({{$prefix}}TraceIdKey, traceId)
+	}
+	requestId = {{$prefix}}newTraceId(8)
+	out.Set({{$prefix}}RequestIdKey, requestId)
+	return out, traceId, requestId
+}
+
+// 鉴权级别
+type {{$prefix}}AuthLevel int
+
+const (
+	{{$prefix}}AuthNone     {{$prefix}}AuthLevel = iota // 登录前可调用
+	{{$prefix}}AuthRequired                             // 需要已登录的会话
+	{{$prefix}}AuthRole                                 // 需要会话具有指定角色
+)
+
+// 方法的鉴权要求(@auth)
+type {{$prefix}}AuthPolicy struct {
+	Level {{$prefix}}AuthLevel
+	Role  string // Level为AuthRole时的角色名
+}
+
+func (p {{$prefix}}AuthPolicy) String() string {
+	switch p.Level {
+	case {{$prefix}}AuthRequired:
+		return "required"
+	case {{$prefix}}AuthRole:
+		return "role:" + p.Role
+	}
+	return "none"
+}
+
+// 根据@upid获取方法的鉴权要求
+func {{$prefix}}AuthPolicyById(id uint16) ({{$prefix}}AuthPolicy, bool) {
+	info, ok := {{$prefix}}meth2route[{{$prefix}}id2meth[id]]
+	return info.Auth, ok
+}
+
+// 会话检查, 由网关根据TransmitArgs(SessionId/MD等)判断会话是否满足鉴权要求, 需并发安全
+type {{$prefix}}SessionChecker interface {
+	// 返回错误则拒绝转发, 非TransmitError的错误会转换为ErrCodeAuth
+	CheckAuth(ctx context.Context, args *{{$prefix}}TransmitArgs, policy {{$prefix}}AuthPolicy) error
+}
+
+// 设置会话检查器, 应在开始转发前调用
+// 未设置时需要鉴权(非none)的方法一律拒绝
+func {{$prefix}}SetSessionChecker(checker {{$prefix}}SessionChecker) {
+	{{$prefix}}checkerLock.Lock()
+	defer {{$prefix}}checkerLock.Unlock()
+	{{$prefix}}sessionChecker = checker
+}
+
+func {{$prefix}}getSessionChecker() {{$prefix}}SessionChecker {
+	{{$prefix}}checkerLock.RLock()
+	defer {{$prefix}}checkerLock.RUnlock()
+	return {{$prefix}}sessionChecker
+}
+
+// 检查方法的鉴权要求, @auth none 直接通过
+func {{$prefix}}checkAuth(ctx context.Context, args *{{$prefix}}TransmitArgs) error {
+	policy := {{$prefix}}meth2route[args.Method].Auth
+	if policy.Level == {{$prefix}}AuthNone {
+		return nil
+	}
+	checker := {{$prefix}}getSessionChecker()
+	if checker == nil {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeAuth, "no session checker for "+policy.String())
+	}
+	if err := checker.CheckAuth(ctx, args, policy); err != nil {
+		if te, ok := err.(*{{$prefix}}TransmitError); ok {
+			return te
+		}
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeAuth, err.Error())
+	}
+	return nil
+}
+
+var {{$prefix}}ErrRateLimited = {{$prefix}}newTransmitError({{$prefix}}ErrCodeRateLimit, "rate limited")
+
+// 方法的限流配置(@ratelimit)
+type {{$prefix}}RateLimit struct {
+	Rate  float64 // 每秒产生的令牌数
+	Burst int     // 桶容量
+	By    string  // 按该metadata的值分别限流, 为空则整个方法共用一个桶
+}
+
+// 限流存储, 可替换为redis等共享存储, 需并发安全
+type {{$prefix}}LimiterStore interface {
+	// 从key对应的令牌桶中取一个令牌, 返回是否允许
+	Allow(key string, limit {{$prefix}}RateLimit) bool
+}
+
+// 设置限流存储, 应在开始转发前调用
+func {{$prefix}}SetLimiterStore(store {{$prefix}}LimiterStore) {
+	{{$prefix}}limiterLock.Lock()
+	defer {{$prefix}}limiterLock.Unlock()
+	{{$prefix}}limiterStore = store
+}
+
+func {{$prefix}}getLimiterStore() {{$prefix}}LimiterStore {
+	{{$prefix}}limiterLock.RLock()
+	defer {{$prefix}}limiterLock.RUnlock()
+	return {{$prefix}}limiterStore
+}
+
+// 检查方法的限流, 未配置@ratelimit的方法直接通过
+func {{$prefix}}checkRateLimit(args *{{$prefix}}TransmitArgs) error {
+	limit, ok := {{$prefix}}meth2ratelimit[args.Method]
+	if !ok {
+		return nil
+	}
+	key := args.Method
+	if len(limit.By) > 0 {
+		// 缺少该metadata的请求共用一个桶
+		key += "|"
+		if list := args.MD.Get(limit.By); len(list) > 0 {
+			key += list[0]
+		}
+	}
+	if !{{$prefix}}getLimiterStore().Allow(key, limit) {
+		return {{$prefix}}ErrRateLimited
+	}
+	return nil
+}
+
+type {{$prefix}}tokenBucket struct {
+	limit  {{$prefix}}RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// 内存令牌桶, 定期清理已满的桶
+type {{$prefix}}MemoryLimiterStore struct {
+	lock    sync.Mutex
+	buckets map[string]*{{$prefix}}tokenBucket
+	sweep   time.Time
+}
+
+func {{$prefix}}NewMemoryLimiterStore() *{{$prefix}}MemoryLimiterStore {
+	return &{{$prefix}}MemoryLimiterStore{
+		buckets: map[string]*{{$prefix}}tokenBucket{},
+		sweep:   time.Now(),
+	}
+}
+
+func (p *{{$prefix}}MemoryLimiterStore) Allow(key string, limit {{$prefix}}RateLimit) bool {
+	now := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if now.Sub(p.sweep) > time.Minute {
+		p.sweep = now
+		for k, b := range p.buckets {
+			// 已回满的桶与新建的桶等价
+			if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+				delete(p.buckets, k)
+			}
+		}
+	}
+	b, ok := p.buckets[key]
+	if !ok {
+		b = &{{$prefix}}tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		p.buckets[key] = b
+	} else {
+		b.limit = limit
+		b.tokens += now.Sub(b.last).Seconds() * limit.Rate
+		if b.tokens > float64(limit.Burst) {
+			b.tokens = float64(limit.Burst)
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func {{$prefix}}RegisterTransmitor(args *{{$prefix}}TransmitArgs) error {
+	if err := {{$prefix}}checkTransmitArgs(args); err != nil {
+		return err
+	}
+
+	packageName, serviceName, _, err := {{$prefix}}ParseMethod(args.Method)
+	if err != nil {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, err.Error())
+	}
+	packageService := packageName + "." + serviceName
+	if handler, ok := {{$prefix}}serviceMap[packageService]; ok {
+{{- if .WithClientStream}}
+		if {{$prefix}}meth2route[args.Method].ClientStream {
+			return {{$prefix}}transmitStream(args, handler)
+		}
+{{- end}}
+		err := handler(args)
+		return err
+	}
+	return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, "method not register yet")
+}
+
+// 调用方已返回后的失败: 回调ErrorCallback{{if .ErrorReply}}, 未设置时以错误响应回调DoneCallback{{else}}, 未设置时忽略{{end}}
+func {{$prefix}}reportError(args *{{$prefix}}TransmitArgs, err error) {
+	if args.ErrorCallback != nil {
+		args.ErrorCallback(err)
+		return
+	}
+{{- if .ErrorReply}}
+	args.DoneCallback({{$prefix}}NewErrorReply(err))
+{{- end}}
+}
+
+var (
+	{{$prefix}}ErrQueueFull        = {{$prefix}}newTransmitError({{$prefix}}ErrCodeQueueFull, "transmit queue full")
+	{{$prefix}}ErrTransmitorClosed = {{$prefix}}newTransmitError({{$prefix}}ErrCodeClosed, "transmitor closed")
+)
+
+// 后端服务的队列限制
+type {{$prefix}}QueueLimit struct {
+	Workers   int // worker数量, 至少为1
+	QueueSize int // 排队上限, 超过时返回ErrQueueFull
+}
+
+// 异步转发器: 按后端服务(package.TargetService)分队列, 每个队列由固定数量的worker调用RegisterTransmitor,
+// 完成时回调DoneCallback, 失败时回调ErrorCallback。
+// 注意: 服务端流在流结束前会一直占用一个worker
+type {{$prefix}}AsyncTransmitor struct {
+	lock   sync.Mutex
+	def    {{$prefix}}QueueLimit
+	limits map[string]{{$prefix}}QueueLimit
+	queues map[string]chan *{{$prefix}}TransmitArgs
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// def 为各后端服务的默认队列限制
+func {{$prefix}}NewAsyncTransmitor(def {{$prefix}}QueueLimit) *{{$prefix}}AsyncTransmitor {
+	return &{{$prefix}}AsyncTransmitor{
+		def:    def,
+		limits: map[string]{{$prefix}}QueueLimit{},
+		queues: map[string]chan *{{$prefix}}TransmitArgs{},
+	}
+}
+
+// 设置某个后端服务(package.TargetService)的队列限制, 需在该服务第一次Transmit前调用
+func (p *{{$prefix}}AsyncTransmitor) SetLimit(service string, limit {{$prefix}}QueueLimit) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.limits[service] = limit
+}
+
+// 将args放入对应后端服务的队列后立即返回, 队列满时返回ErrQueueFull
+func (p *{{$prefix}}AsyncTransmitor) Transmit(args *{{$prefix}}TransmitArgs) error {
+	if err := {{$prefix}}checkTransmitArgs(args); err != nil {
+		return err
+	}
+{{- if .WithClientStream}}
+	// 已打开的客户端流直接发送, 保证同一流上的数据包顺序
+	if h, ok := {{$prefix}}getStream(args.SessionId, args.Method); ok {
+		return {{$prefix}}sendStream(h, args)
+	}
+{{- end}}
+	packageName, serviceName, _, err := {{$prefix}}ParseMethod(args.Method)
+	if err != nil {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, err.Error())
+	}
+	service := packageName + "." + serviceName
+	if _, ok := {{$prefix}}serviceMap[service]; !ok {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, "method not register yet")
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return {{$prefix}}ErrTransmitorClosed
+	}
+	q, ok := p.queues[service]
+	if !ok {
+		limit, ok := p.limits[service]
+		if !ok {
+			limit = p.def
+		}
+		if limit.Workers < 1 {
+			limit.Workers = 1
+		}
+		if limit.QueueSize < 0 {
+			limit.QueueSize = 0
+		}
+		q = make(chan *{{$prefix}}TransmitArgs, limit.QueueSize)
+		p.queues[service] = q
+		for i := 0; i < limit.Workers; i++ {
+			p.wg.Add(1)
+			go p.work(q)
+		}
+	}
+	select {
+	case q <- args:
+		return nil
+	default:
+		return {{$prefix}}ErrQueueFull
+	}
+}
+
+func (p *{{$prefix}}AsyncTransmitor) work(q chan *{{$prefix}}TransmitArgs) {
+	defer p.wg.Done()
+	for args := range q {
+		if err := {{$prefix}}RegisterTransmitor(args); err != nil {
+			{{$prefix}}reportError(args, err)
+		}
+	}
+}
+
+// 停止接收新的请求, 等待已排队的请求执行完毕
+func (p *{{$prefix}}AsyncTransmitor) Close() {
+	p.lock.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.lock.Unlock()
+	p.wg.Wait()
+}
+
+// 未设置TransmitArgs.Conn时使用的连接管理器, 可在开始转发前替换
+var {{$prefix}}DefaultConnManager = {{$prefix}}NewConnManager(5 * time.Minute)
+
+type {{$prefix}}connEntry struct {
+	conn    *grpc.ClientConn
+	refs    int       // 正在使用的调用数
+	lastUse time.Time // 最后一次释放的时间
+}
+
+// 后端连接管理: 按Endpoint缓存grpc.ClientConn供所有调用复用,
+// 定期检查连接状态, 关闭已失效(Shutdown/TransientFailure)及空闲超时的连接, 下次使用时重新连接
+type {{$prefix}}ConnManager struct {
+	lock        sync.Mutex
+	idleTimeout time.Duration
+	dialOpts    []grpc.DialOption
+	conns       map[string]*{{$prefix}}connEntry
+	once        sync.Once
+	done        chan struct{}
+	closed      bool
+}
+
+// idleTimeout: 空闲超过该时间的连接被关闭, <=0表示不关闭空闲连接; opts: 所有连接共用的DialOption
+func {{$prefix}}NewConnManager(idleTimeout time.Duration, opts ...grpc.DialOption) *{{$prefix}}ConnManager {
+	return &{{$prefix}}ConnManager{
+		idleTimeout: idleTimeout,
+		dialOpts:    opts,
+		conns:       map[string]*{{$prefix}}connEntry{},
+		done:        make(chan struct{}),
+	}
+}
+
+// 获取endpoint的连接, 使用完毕后调用release; 首次连接时使用管理器的DialOption及opts
+func (p *{{$prefix}}ConnManager) Get(ctx context.Context, endpoint string, opts ...grpc.DialOption) (conn *grpc.ClientConn, release func(), err error) {
+	p.once.Do(func() {
+		go p.checkLoop()
+	})
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, nil, {{$prefix}}newTransmitError({{$prefix}}ErrCodeClosed, "conn manager closed")
+	}
+	entry, ok := p.conns[endpoint]
+	if ok && entry.conn.GetState() == connectivity.Shutdown {
+		delete(p.conns, endpoint)
+		ok = false
+	}
+	if !ok {
+		// 拨号可能阻塞(WithBlock), 不持有锁
+		p.lock.Unlock()
+		newConn, err := grpc.DialContext(ctx, endpoint, append(append([]grpc.DialOption{}, p.dialOpts...), opts...)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.lock.Lock()
+		if p.closed {
+			p.lock.Unlock()
+			newConn.Close()
+			return nil, nil, {{$prefix}}newTransmitError({{$prefix}}ErrCodeClosed, "conn manager closed")
+		}
+		if entry, ok = p.conns[endpoint]; ok {
+			// 其他调用已建立连接
+			newConn.Close()
+		} else {
+			entry = &{{$prefix}}connEntry{conn: newConn}
+			p.conns[endpoint] = entry
+		}
+	}
+	entry.refs++
+	p.lock.Unlock()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			p.lock.Lock()
+			entry.refs--
+			entry.lastUse = time.Now()
+			p.lock.Unlock()
+		})
+	}
+	return entry.conn, release, nil
+}
+
+func (p *{{$prefix}}ConnManager) checkLoop() {
+	interval := time.Minute
+	if p.idleTimeout > 0 && p.idleTimeout/2 < interval {
+		interval = p.idleTimeout / 2
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+// 关闭已失效及空闲超时的连接, 正在使用的连接不会被关闭
+func (p *{{$prefix}}ConnManager) check() {
+	var list []*grpc.ClientConn
+	now := time.Now()
+	p.lock.Lock()
+	for endpoint, entry := range p.conns {
+		state := entry.conn.GetState()
+		idle := entry.refs == 0 && p.idleTimeout > 0 && now.Sub(entry.lastUse) > p.idleTimeout
+		broken := state == connectivity.Shutdown || (entry.refs == 0 && state == connectivity.TransientFailure)
+		if idle || broken {
+			delete(p.conns, endpoint)
+			list = append(list, entry.conn)
+		}
+	}
+	p.lock.Unlock()
+	for _, conn := range list {
+		conn.Close()
+	}
+}
+
+// 关闭所有连接, 之后Get返回错误
+func (p *{{$prefix}}ConnManager) Close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	conns := p.conns
+	p.conns = map[string]*{{$prefix}}connEntry{}
+	p.lock.Unlock()
+	for _, entry := range conns {
+		entry.conn.Close()
+	}
+}
+{{if .WithClientStream}}
+func {{$prefix}}getStream(session uint64, method string) (*{{$prefix}}streamHandle, bool) {
+	{{$prefix}}streamLock.Lock()
+	defer {{$prefix}}streamLock.Unlock()
+	h, ok := {{$prefix}}streamMap[{{$prefix}}streamKey{session: session, method: method}]
+	return h, ok
+}
+
+// 在已打开的流上发送后续数据包, 与打开流时一样检查鉴权/限流并经过拦截器, 此时next只发送数据包, 返回nil
+func {{$prefix}}sendStream(h *{{$prefix}}streamHandle, args *{{$prefix}}TransmitArgs) error {
+	parent := args.Ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if err := {{$prefix}}checkAuth(parent, args); err != nil {
+		return err
+	}
+	if err := {{$prefix}}checkRateLimit(args); err != nil {
+		return err
+	}
+	_, err := {{$prefix}}chainInterceptors({{$prefix}}meth2route[args.Method], func(ctx context.Context, args *{{$prefix}}TransmitArgs) (proto.Message, error) {
+		return nil, h.Send(args.Data, args.Codec)
+	})(metadata.NewOutgoingContext(parent, args.MD), args)
+	return err
+}
+
+// 取得会话上该方法的流, 不存在则登记一个新句柄, created表示需要由本次调用打开
+func {{$prefix}}acquireStream(args *{{$prefix}}TransmitArgs) (h *{{$prefix}}streamHandle, created bool) {
+	{{$prefix}}streamLock.Lock()
+	defer {{$prefix}}streamLock.Unlock()
+	key := {{$prefix}}streamKey{session: args.SessionId, method: args.Method}
+	if h, ok := {{$prefix}}streamMap[key]; ok {
+		return h, false
+	}
+	h = {{$prefix}}newStreamHandle()
+	{{$prefix}}streamMap[key] = h
+	return h, true
+}
+
+// 客户端流/双向流: 首个数据包登记流并在后台打开, 首个数据包发送到后端后即返回, 不阻塞会话的读循环;
+// 同一会话的其他数据包(包括打开期间并发到达的)等待打开完成后在该流上发送。
+// 流打开之后的失败(包括客户端流的响应失败)通过reportError回调
+func {{$prefix}}transmitStream(args *{{$prefix}}TransmitArgs, handler {{$prefix}}registerHandler) error {
+	h, created := {{$prefix}}acquireStream(args)
+	if !created {
+		return {{$prefix}}sendStream(h, args)
+	}
+	args.stream = h
+	go func() {
+		err := handler(args)
+		{{$prefix}}removeStream(args, h)
+		if !h.setReady(err) && err != nil {
+			{{$prefix}}reportError(args, err)
+		}
+	}()
+	<-h.ready
+	return h.err
+}
+
+func {{$prefix}}removeStream(args *{{$prefix}}TransmitArgs, h *{{$prefix}}streamHandle) {
+	{{$prefix}}streamLock.Lock()
+	key := {{$prefix}}streamKey{session: args.SessionId, method: args.Method}
+	if {{$prefix}}streamMap[key] == h {
+		delete({{$prefix}}streamMap, key)
+	}
+	{{$prefix}}streamLock.Unlock()
+	h.Close()
+}
+
+// 结束会话上某个方法(package.TargetService/Method)的客户端流, 客户端流随后返回响应
+func {{$prefix}}CloseStream(session uint64, method string) bool {
+	h, ok := {{$prefix}}getStream(session, method)
+	if ok {
+		h.Close()
+	}
+	return ok
+}
+
+// 会话断开时结束该会话上的所有客户端流
+func {{$prefix}}CloseSessionStreams(session uint64) {
+	var list []*{{$prefix}}streamHandle
+	{{$prefix}}streamLock.Lock()
+	for k, h := range {{$prefix}}streamMap {
+		if k.session == session {
+			list = append(list, h)
+		}
+	}
+	{{$prefix}}streamLock.Unlock()
+	for _, h := range list {
+		h.Close()
+	}
+}
+{{end}}
+`))
+
+	transTamplate = template.Must(template.New("meth").Parse(`
+{{define "callctx"}}
+{{- if .GetTimeoutExpr}}
+	ctx, cancel := context.WithTimeout(args.ctx, {{.GetTimeoutExpr}})
+{{- else}}
+	ctx, cancel := context.WithCancel(args.ctx)
+{{- end}}
+	defer cancel()
+{{- end}}
+// registor single service enter point
+{{$prefix := .DefinePrefix}}
+{{range $svc := .ServicesWithComment}}
+// *********************************************************************************
+// 注册{{$svc.GetName}}传输转换入口
+{{if $svc.Comment}}{{$svc.GetFormatComment}}{{end}}
+func {{$prefix}}register_{{$svc.TargetName}}_Transmitor(args *{{$prefix}}TransmitArgs) (err error) {
+	args.stat = nil
+	if sink := {{$prefix}}getMetricsSink(); sink != nil {
+		args.stat = &{{$prefix}}callStat{start: time.Now()}
+		args.stat.m.Route, _ = {{$prefix}}GetRouteInfo(args.Method)
+		defer func(stat *{{$prefix}}callStat) {
+			stat.finish(sink, err)
+		}(args.stat)
+	}
+	parent := args.Ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if err := {{$prefix}}checkAuth(parent, args); err != nil {
+		return err
+	}
+	if err := {{$prefix}}checkRateLimit(args); err != nil {
+		return err
+	}
+	conn := args.Conn
+	if conn == nil {
+		c, release, err := {{$prefix}}DefaultConnManager.Get(parent, args.Endpoint, args.Opts...)
+		if err != nil {
+			return {{$prefix}}newTransmitError({{$prefix}}ErrCodeDial, err.Error())
+		}
+		defer release()
+		conn = c
+	}
+	
+	md, traceId, requestId := {{$prefix}}traceMD(args.MD)
+	args.ctx = metadata.NewOutgoingContext(parent, md)
+	//
+	client := {{$svc.TargetPkg}}New{{$svc.TargetName}}Client(conn)
+	handler, ok := {{$prefix}}transmit_{{$svc.TargetName}}_Map[args.Method]
+	if !ok {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, "method not register yet")
+	}
+	info := {{$prefix}}meth2route[args.Method]
+	if tracer := {{$prefix}}getTracer(); tracer != nil {
+		var span {{$prefix}}Span
+		args.ctx, span = tracer.StartSpan(args.ctx, info, traceId, requestId)
+		defer func() {
+			span.Finish(err)
+		}()
+	}
+	res, err := {{$prefix}}chainInterceptors(info, func(ctx context.Context, args *{{$prefix}}TransmitArgs) (proto.Message, error) {
+		args.ctx = ctx
+		return handler(args, client)
+	})(args.ctx, args)
+	if err != nil {
+		return err
+	}
+	// 流式方法已在handler内逐条回调
+	if res != nil {
+		{{$prefix}}doneCallback(args, res)
+	}
+	return nil
+}
+
+{{range $m := $svc.MethodsWithComment}}
 // 注册{{$svc.TargetName}}/{{$m.GetName}} 传输方法入口
 {{if $m.Comment}}{{$m.GetFormatComment}}{{end}}
 func {{$prefix}}request_{{$svc.TargetName}}_{{$m.GetName}}(args *{{$prefix}}TransmitArgs, client {{$svc.TargetPkg}}{{$svc.TargetName}}Client) (proto.Message, error) {
+{{- if or $m.IsClientStream $m.IsBidiStream}}
+{{- template "callctx" $m}}
+	stream, err := client.{{$m.GetName}}(ctx)
+	if err != nil {
+		return nil, {{$prefix}}ToTransmitError(err)
+	}
+	send := func(data []byte, codec uint16) error {
+		protoReq := &{{$m.GetRequestPackage}}{{$m.RequestType.GetName}}{}
+		if err := {{$prefix}}decodeRequest(args, data, codec, protoReq); err != nil {
+			return {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
+		}
+		if err := stream.Send(protoReq); err != nil {
+			return {{$prefix}}ToTransmitError(err)
+		}
+		return nil
+	}
+	// 首个数据包发送成功后其他数据包才能在流上发送
+	if err := send(args.Data, args.Codec); err != nil {
+		return nil, err
+	}
+	h := args.stream
+{{- if $m.IsBidiStream}}
+	h.open(send, stream.CloseSend)
+{{- else}}
+	h.open(send, nil)
+{{- end}}
+{{- if $m.IsBidiStream}}
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, {{$prefix}}ToTransmitError(err)
+		}
+		{{$prefix}}doneCallback(args, reply)
+	}
+	if args.StreamEndCallback != nil {
+		args.StreamEndCallback()
+	}
+	return nil, nil
+{{- else}}
+	// 等待CloseStream/CloseSessionStreams结束发送
+	select {
+	case <-h.closed:
+	case <-stream.Context().Done():
+	}
+	h.Close()
+	reply, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, {{$prefix}}ToTransmitError(err)
+	}
+	return reply, nil
+{{- end}}
+{{- else}}
 	protoReq := &{{$m.GetRequestPackage}}{{$m.RequestType.GetName}}{}
-	if err := {{$prefix}}DecodeBytes(args.Data, args.Codec, protoReq); err != nil {
-		return nil, errors.New("codec err["+err.Error()+"]")
+	if err := {{$prefix}}decodeRequest(args, args.Data, args.Codec, protoReq); err != nil {
+		return nil, {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
 	}
-	reply, err := client.{{$m.GetName}}(args.ctx, protoReq)
+{{- template "callctx" $m}}
+{{- if $m.IsServerStream}}
+	stream, err := client.{{$m.GetName}}(ctx, protoReq)
 	if err != nil {
-		return nil, errors.New("call err["+err.Error()+"]")
+		return nil, {{$prefix}}ToTransmitError(err)
+	}
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, {{$prefix}}ToTransmitError(err)
+		}
+		{{$prefix}}doneCallback(args, reply)
+	}
+	if args.StreamEndCallback != nil {
+		args.StreamEndCallback()
+	}
+	return nil, nil
+{{- else}}
+	reply, err := client.{{$m.GetName}}(ctx, protoReq)
+	if err != nil {
+		return nil, {{$prefix}}ToTransmitError(err)
 	}
 	return reply, nil
+{{- end}}
+{{- end}}
 }
 {{end}}
 {{end}}
+`))
+	clientTemplate = template.Must(template.New("client").Parse(`
+{{$prefix := .DefinePrefix}}
+// *********************************************************************************
+// 网关客户端(插件参数 go_client), 用于集成测试及机器人
+
+// 客户端包头长度: Length(body长度), Seq, Id, Codec, 均为uint16小端
+const {{$prefix}}ClientHeadSize = 8
+
+// 网关数据包
+type {{$prefix}}ClientPack struct {
+	Seq   uint16
+	Id    uint16
+	Codec uint16
+	Body  []byte
+}
+
+// 网关客户端: 按seq匹配响应, 推送协议(@push)交给HandlePush注册的函数处理
+type {{$prefix}}Client struct {
+	conn    net.Conn
+	codec   uint16
+	Timeout time.Duration // ctx没有deadline时的调用超时, 默认10s
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	seq       uint16
+	pending   map[uint16]chan *{{$prefix}}ClientPack
+	pushes    map[uint16]func(proto.Message)
+	done      chan struct{}
+	err       error
+}
+
+// conn为已连接的网关链接, codec为请求使用的编码(可带压缩标记)
+func {{$prefix}}NewClient(conn net.Conn, codec uint16) *{{$prefix}}Client {
+	c := &{{$prefix}}Client{
+		conn:    conn,
+		codec:   codec,
+		Timeout: 10 * time.Second,
+		pending: map[uint16]chan *{{$prefix}}ClientPack{},
+		pushes:  map[uint16]func(proto.Message){},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// 注册推送协议的处理函数, 在读goroutine中调用, 不能阻塞
+func (c *{{$prefix}}Client) HandlePush(id uint16, handler func(proto.Message)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pushes[id] = handler
+}
+
+// 连接断开时关闭
+func (c *{{$prefix}}Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// 连接断开的原因
+func (c *{{$prefix}}Client) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+func (c *{{$prefix}}Client) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+func (c *{{$prefix}}Client) writePack(seq, id, codec uint16, body []byte) error {
+	if len(body) > 0xffff {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeArgs, fmt.Sprintf("body too large: %d", len(body)))
+	}
+	buf := make([]byte, {{$prefix}}ClientHeadSize+len(body))
+	binary.LittleEndian.PutUint16(buf[0:], uint16(len(body)))
+	binary.LittleEndian.PutUint16(buf[2:], seq)
+	binary.LittleEndian.PutUint16(buf[4:], id)
+	binary.LittleEndian.PutUint16(buf[6:], codec)
+	copy(buf[{{$prefix}}ClientHeadSize:], body)
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *{{$prefix}}Client) readLoop() {
+	head := make([]byte, {{$prefix}}ClientHeadSize)
+	for {
+		if _, err := io.ReadFull(c.conn, head); err != nil {
+			c.fail(err)
+			return
+		}
+		pack := &{{$prefix}}ClientPack{
+			Seq:   binary.LittleEndian.Uint16(head[2:]),
+			Id:    binary.LittleEndian.Uint16(head[4:]),
+			Codec: binary.LittleEndian.Uint16(head[6:]),
+			Body:  make([]byte, binary.LittleEndian.Uint16(head[0:])),
+		}
+		if _, err := io.ReadFull(c.conn, pack.Body); err != nil {
+			c.fail(err)
+			return
+		}
+		c.lock.Lock()
+		if !{{$prefix}}pushIds[pack.Id] {
+			if ch, ok := c.pending[pack.Seq]; ok {
+				delete(c.pending, pack.Seq)
+				c.lock.Unlock()
+				ch <- pack
+				continue
+			}
+		}
+		handler := c.pushes[pack.Id]
+		c.lock.Unlock()
+		// 未注册处理函数或无法解析的推送直接丢弃
+		if handler == nil {
+			continue
+		}
+		msg, ok := {{$prefix}}GetMsgObjById(pack.Id)
+		if !ok || {{$prefix}}DecodeBytes(pack.Body, pack.Codec, msg) != nil {
+			continue
+		}
+		handler(msg)
+	}
+}
+
+func (c *{{$prefix}}Client) fail(err error) {
+	c.lock.Lock()
+	c.err = err
+	for seq, ch := range c.pending {
+		close(ch)
+		delete(c.pending, seq)
+	}
+	c.lock.Unlock()
+	close(c.done)
+}
+
+// 发送@upid为upId的请求, 等待相同seq的响应并解码到resp
+// downId为响应的@downid, 响应包的id不一致时返回ErrCodeMethod; 为0时不检查
+func (c *{{$prefix}}Client) Invoke(ctx context.Context, upId, downId uint16, req, resp proto.Message) error {
+	body, err := {{$prefix}}EncodeBytes(c.codec, req)
+	if err != nil {
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
+	}
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	ch := make(chan *{{$prefix}}ClientPack, 1)
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return c.err
+	}
+	c.seq++
+	if c.seq == 0 {
+		c.seq = 1
+	}
+	seq := c.seq
+	if _, ok := c.pending[seq]; ok {
+		c.lock.Unlock()
+		return {{$prefix}}newTransmitError({{$prefix}}ErrCodeQueueFull, "too many pending requests")
+	}
+	c.pending[seq] = ch
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		if c.pending[seq] == ch {
+			delete(c.pending, seq)
+		}
+		c.lock.Unlock()
+	}()
+
+	if err := c.writePack(seq, upId, c.codec, body); err != nil {
+		return err
+	}
+	select {
+	case pack, ok := <-ch:
+		if !ok {
+			return c.Err()
+		}
+{{- with .ErrorReply}}
+		if pack.Id == {{.Id}} {
+			reply := &{{.GetGoType}}{}
+			if err := {{$prefix}}DecodeBytes(pack.Body, pack.Codec, reply); err != nil {
+				return {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
+			}
+			return {{$prefix}}newTransmitError(uint32(reply.{{.CodeField}}), {{if .MsgField}}reply.{{.MsgField}}{{else}}""{{end}})
+		}
+{{- end}}
+		if downId != 0 && pack.Id != downId {
+			return {{$prefix}}newTransmitError({{$prefix}}ErrCodeMethod, fmt.Sprintf("unexpected response id %d, want %d", pack.Id, downId))
+		}
+		if err := {{$prefix}}DecodeBytes(pack.Body, pack.Codec, resp); err != nil {
+			return {{$prefix}}newTransmitError({{$prefix}}ErrCodeCodec, err.Error())
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+{{range $m := .ClientMethods}}
+{{if $m.Comment}}{{$m.GetFormatComment}}{{end}}
+func (c *{{$prefix}}Client) {{$m.GetName}}(ctx context.Context, req *{{$m.GetRequestPackage}}{{$m.RequestType.GetName}}) (*{{$m.GetResponsePackage}}{{$m.ResponseType.GetName}}, error) {
+	resp := &{{$m.GetResponsePackage}}{{$m.ResponseType.GetName}}{}
+	if err := c.Invoke(ctx, {{$m.GetUpId}}, {{$m.GetDownId}}, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+{{end}}
 `))
 )
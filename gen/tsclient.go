@@ -0,0 +1,690 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: tsclient.go
+ * @time: 2026/10/18 22:10
+ */
+package gen
+
+import (
+	`bytes`
+	`fmt`
+	`strings`
+	`text/template`
+
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	`github.com/golang/protobuf/protoc-gen-go/generator`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+type tsField struct {
+	Name string // json字段名
+	Type string
+}
+
+type tsInterface struct {
+	Name     string
+	FullName string // package.Message
+	Fields   []*tsField
+}
+
+type tsMethod struct {
+	*routeMethod
+	FuncName string // 客户端方法名, lowerCamelCase
+	ReqType  string // ts接口名
+	RespType string
+	ReqName  string // 协议全名, 交给BodyCodec
+	RespName string
+}
+
+type tsId struct {
+	Name     string
+	Id       uint16
+	FullName string // 协议全名
+}
+
+type tsParam struct {
+	Source     string
+	Methods    []*tsMethod
+	Calls      []*tsMethod // 单次调用的方法, 生成客户端方法
+	Pushes     []*tsId
+	Types      []*tsId // id => 协议, 包括请求/响应/推送/声明
+	Interfaces []*tsInterface
+	ErrorReply *tsErrorReply
+}
+
+type tsErrorReply struct {
+	Id        uint16
+	CodeField string
+	MsgField  string
+}
+
+// 客户端类自身的成员, 生成的方法名不能与之冲突
+var tsReservedNames = map[string]bool{
+	"connect": true, "close": true, "onPush": true, "invoke": true, "url": true, "socket": true, "seq": true,
+	"pending": true, "pushHandlers": true, "options": true, "codecs": true, "onData": true, "decodeBody": true, "failAll": true,
+}
+
+// 模块自身的导出/内部名称及模块中用到的全局类型, 协议接口名不能与之相同
+var tsTakenNames = map[string]bool{
+	"HeadSize": true, "CodecProto": true, "CodecJson": true, "CodecFormatMask": true, "CodecFlagGzip": true, "CodecFlagSnappy": true,
+	"ErrCode": true, "UpId": true, "DownId": true, "PushId": true, "Id2Method": true, "Method2Id": true, "Id2Type": true,
+	"Pack": true, "BodyCodec": true, "JsonCodec": true, "GateError": true, "SocketLike": true, "ClientOptions": true,
+	"PendingCall": true, "GateClient": true,
+	"Error": true, "Object": true, "Array": true, "String": true, "Number": true, "Boolean": true, "Function": true, "Symbol": true,
+	"Promise": true, "Map": true, "Set": true, "Date": true, "JSON": true, "Math": true, "RegExp": true, "Record": true,
+	"ArrayBuffer": true, "Uint8Array": true, "DataView": true, "WebSocket": true,
+}
+
+// 常用的google.protobuf类型在json中的表示
+var tsWellKnown = map[string]string{
+	".google.protobuf.Timestamp":   "string",
+	".google.protobuf.Duration":    "string",
+	".google.protobuf.Empty":       "{}",
+	".google.protobuf.StringValue": "string | null",
+	".google.protobuf.BytesValue":  "string | null",
+	".google.protobuf.BoolValue":   "boolean | null",
+	".google.protobuf.Int32Value":  "number | null",
+	".google.protobuf.UInt32Value": "number | null",
+	".google.protobuf.FloatValue":  "number | null",
+	".google.protobuf.DoubleValue": "number | null",
+	".google.protobuf.Int64Value":  "string | number | null",
+	".google.protobuf.UInt64Value": "string | number | null",
+}
+
+// ts中的协议类型, 同名的协议加上包名区分
+type tsTypes struct {
+	reg      *Registry
+	origName bool              // 插件参数 json_orig_name
+	names    map[string]string // FQMN => 接口名
+	list     []*descriptor.Message
+}
+
+func (p *tsTypes) add(msg *descriptor.Message) {
+	fqmn := msg.FQMN()
+	if _, ok := p.names[fqmn]; ok || tsWellKnown[fqmn] != "" || strings.HasPrefix(fqmn, ".google.protobuf.") {
+		return
+	}
+	p.names[fqmn] = ""
+	p.list = append(p.list, msg)
+	for _, f := range msg.Fields {
+		if f.GetType() != descriptor2.FieldDescriptorProto_TYPE_MESSAGE {
+			continue
+		}
+		if sub, err := p.reg.LookupMsg("", f.GetTypeName()); err == nil {
+			if sub.GetOptions().GetMapEntry() {
+				for _, kv := range sub.Fields {
+					if kv.GetType() == descriptor2.FieldDescriptorProto_TYPE_MESSAGE {
+						if v, err := p.reg.LookupMsg("", kv.GetTypeName()); err == nil {
+							p.add(v)
+						}
+					}
+				}
+				continue
+			}
+			p.add(sub)
+		}
+	}
+}
+
+// 分配接口名: 默认为协议名(嵌套协议为 Outer_Inner), 重名或与模块/全局名称冲突时加上包名
+func (p *tsTypes) resolve() {
+	localName := func(msg *descriptor.Message) string {
+		return strings.Join(append(append([]string{}, msg.Outers...), msg.GetName()), "_")
+	}
+	count := map[string]int{}
+	for _, msg := range p.list {
+		count[localName(msg)]++
+	}
+	for _, msg := range p.list {
+		name := localName(msg)
+		if count[name] > 1 || tsTakenNames[name] {
+			name = generator.CamelCase(strings.Replace(msg.File.GetPackage(), ".", "_", -1)) + "_" + name
+		}
+		p.names[msg.FQMN()] = name
+	}
+}
+
+func (p *tsTypes) jsonName(f *descriptor.Field) string {
+	if p.origName || len(f.GetJsonName()) < 1 {
+		return f.GetName()
+	}
+	return f.GetJsonName()
+}
+
+func (p *tsTypes) fieldType(f *descriptor.Field) string {
+	var typ string
+	switch f.GetType() {
+	case descriptor2.FieldDescriptorProto_TYPE_DOUBLE, descriptor2.FieldDescriptorProto_TYPE_FLOAT,
+		descriptor2.FieldDescriptorProto_TYPE_INT32, descriptor2.FieldDescriptorProto_TYPE_UINT32,
+		descriptor2.FieldDescriptorProto_TYPE_SINT32, descriptor2.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor2.FieldDescriptorProto_TYPE_SFIXED32:
+		typ = "number"
+	case descriptor2.FieldDescriptorProto_TYPE_INT64, descriptor2.FieldDescriptorProto_TYPE_UINT64,
+		descriptor2.FieldDescriptorProto_TYPE_SINT64, descriptor2.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor2.FieldDescriptorProto_TYPE_SFIXED64:
+		// json中64位整数为字符串
+		typ = "string | number"
+	case descriptor2.FieldDescriptorProto_TYPE_BOOL:
+		typ = "boolean"
+	case descriptor2.FieldDescriptorProto_TYPE_STRING, descriptor2.FieldDescriptorProto_TYPE_BYTES:
+		typ = "string"
+	case descriptor2.FieldDescriptorProto_TYPE_ENUM:
+		typ = "string | number"
+	case descriptor2.FieldDescriptorProto_TYPE_MESSAGE:
+		typ = "any"
+		if wk, ok := tsWellKnown[f.GetTypeName()]; ok {
+			typ = wk
+		} else if sub, err := p.reg.LookupMsg("", f.GetTypeName()); err == nil {
+			if sub.GetOptions().GetMapEntry() && len(sub.Fields) == 2 {
+				return "{ [key: string]: " + p.fieldType(sub.Fields[1]) + " }"
+			}
+			if name := p.names[sub.FQMN()]; len(name) > 0 {
+				typ = name
+			}
+		}
+	default:
+		typ = "any"
+	}
+	if f.GetLabel() == descriptor2.FieldDescriptorProto_LABEL_REPEATED {
+		if strings.Contains(typ, " ") {
+			typ = "(" + typ + ")"
+		}
+		typ += "[]"
+	}
+	return typ
+}
+
+func lowerCamel(name string) string {
+	if len(name) < 1 {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// 生成TypeScript WebSocket客户端
+func (p *TcpGenerator) generateTs(file *descriptor.File) (string, error) {
+	routes, err := p.loadRoutes(file)
+	if err != nil {
+		return "", err
+	}
+
+	types := &tsTypes{reg: p.reg, origName: p.jsonOpts.OrigName, names: map[string]string{}}
+	for _, m := range routes.Methods {
+		types.add(m.Request)
+		types.add(m.Response)
+	}
+	for _, d := range append(append([]*declaredMessage{}, routes.Declares...), routes.Pushes...) {
+		types.add(d.Message)
+	}
+	types.resolve()
+
+	params := tsParam{Source: file.GetName()}
+	for _, msg := range types.list {
+		it := &tsInterface{Name: types.names[msg.FQMN()], FullName: msgFullName(msg)}
+		for _, f := range msg.Fields {
+			it.Fields = append(it.Fields, &tsField{Name: types.jsonName(f), Type: types.fieldType(f)})
+		}
+		params.Interfaces = append(params.Interfaces, it)
+	}
+	typeName := func(msg *descriptor.Message) string {
+		if name := types.names[msg.FQMN()]; len(name) > 0 {
+			return name
+		}
+		return "any"
+	}
+
+	names := map[string]bool{}
+	for _, m := range routes.Methods {
+		it := &tsMethod{
+			routeMethod: m,
+			FuncName:    lowerCamel(m.GetName()),
+			ReqType:     typeName(m.Request),
+			RespType:    typeName(m.Response),
+			ReqName:     msgFullName(m.Request),
+			RespName:    msgFullName(m.Response),
+		}
+		params.Methods = append(params.Methods, it)
+		if m.GetServerStreaming() || m.GetClientStreaming() {
+			continue
+		}
+		if tsReservedNames[it.FuncName] || names[it.FuncName] {
+			return "", fmt.Errorf("ts_client: method name %s conflicts with another client method", m.GetName())
+		}
+		names[it.FuncName] = true
+		params.Calls = append(params.Calls, it)
+	}
+	for _, d := range routes.Pushes {
+		params.Pushes = append(params.Pushes, &tsId{Name: d.GetName(), Id: d.Id, FullName: msgFullName(d.Message)})
+	}
+	for _, it := range routes.Ids() {
+		params.Types = append(params.Types, &tsId{Id: it.Id, FullName: it.FullName()})
+	}
+
+	if errReply := routes.ErrorReply; errReply != nil {
+		it := &tsErrorReply{Id: errReply.Id}
+		for _, f := range errReply.Fields {
+			switch generator.CamelCase(f.GetName()) {
+			case errReply.CodeField:
+				it.CodeField = types.jsonName(f)
+			case errReply.MsgField:
+				it.MsgField = types.jsonName(f)
+			}
+		}
+		params.ErrorReply = it
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := tsTemplate.Execute(out, params); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+var tsTemplate = template.Must(template.New("ts").Parse(`// Code generated by protoc-gen-grpc-tcpgw. DO NOT EDIT.
+// source: {{.Source}}
+/* eslint-disable */
+
+// 包头长度: Length(body长度), Seq, Id, Codec, 均为uint16小端
+export const HeadSize = 8;
+
+// codec: 低8位为编码格式, 高8位为压缩标记
+export const CodecProto = 0;
+export const CodecJson = 1;
+export const CodecFormatMask = 0x00ff;
+export const CodecFlagGzip = 0x0100;
+export const CodecFlagSnappy = 0x0200;
+
+// 网关错误码, 与生成的Go代码一致; 后端错误为grpc状态码(0~16)
+export const ErrCode = {
+  Args: 1000,
+  Codec: 1001,
+  Method: 1002,
+  Dial: 1003,
+  QueueFull: 1004,
+  Closed: 1005,
+  Stream: 1006,
+  RateLimit: 1007,
+  Auth: 1008,
+  DeadlineExceeded: 4,
+};
+
+// 上行请求id(@upid)
+export const UpId = {
+{{- range .Methods}}
+  {{.GetName}}: {{.GetUpId}},
+{{- end}}
+};
+
+// 下行响应id(@downid)
+export const DownId = {
+{{- range .Methods}}{{if .GetDownId}}
+  {{.GetName}}: {{.GetDownId}},
+{{- end}}{{end}}
+};
+
+// 服务端推送id(@push)
+export const PushId = {
+{{- range .Pushes}}
+  {{.Name}}: {{.Id}},
+{{- end}}
+};
+
+// @upid => package.TargetService/Method
+export const Id2Method: { [id: number]: string } = {
+{{- range .Methods}}
+  {{.GetUpId}}: "{{.FullMethod}}",
+{{- end}}
+};
+
+// package.TargetService/Method => @upid
+export const Method2Id: { [method: string]: number } = {
+{{- range .Methods}}
+  "{{.FullMethod}}": {{.GetUpId}},
+{{- end}}
+};
+
+// id => 协议全名(package.Message), 包括请求/响应/推送/声明的协议
+export const Id2Type: { [id: number]: string } = {
+{{- range .Types}}
+  {{.Id}}: "{{.FullName}}",
+{{- end}}
+};
+{{range .Interfaces}}
+// {{.FullName}}
+export interface {{.Name}} {
+{{- range .Fields}}
+  {{.Name}}?: {{.Type}};
+{{- end}}
+}
+{{end}}
+export interface Pack {
+  seq: number;
+  id: number;
+  codec: number;
+  body: Uint8Array;
+}
+
+export function encodePack(pack: Pack): ArrayBuffer {
+  if (pack.body.length > 0xffff) {
+    throw new GateError(ErrCode.Args, "body too large: " + pack.body.length);
+  }
+  const buf = new ArrayBuffer(HeadSize + pack.body.length);
+  const view = new DataView(buf);
+  view.setUint16(0, pack.body.length, true);
+  view.setUint16(2, pack.seq, true);
+  view.setUint16(4, pack.id, true);
+  view.setUint16(6, pack.codec, true);
+  new Uint8Array(buf, HeadSize).set(pack.body);
+  return buf;
+}
+
+// 解析数据包, 一个消息中可能有多个数据包
+export function decodePacks(data: ArrayBuffer | Uint8Array): Pack[] {
+  const bytes = data instanceof Uint8Array ? data : new Uint8Array(data);
+  const view = new DataView(bytes.buffer, bytes.byteOffset, bytes.byteLength);
+  const list: Pack[] = [];
+  let offset = 0;
+  while (offset + HeadSize <= bytes.length) {
+    const length = view.getUint16(offset, true);
+    if (offset + HeadSize + length > bytes.length) {
+      break;
+    }
+    list.push({
+      seq: view.getUint16(offset + 2, true),
+      id: view.getUint16(offset + 4, true),
+      codec: view.getUint16(offset + 6, true),
+      body: bytes.slice(offset + HeadSize, offset + HeadSize + length),
+    });
+    offset += HeadSize + length;
+  }
+  return list;
+}
+
+// 消息体编解码, typeName为协议全名(package.Message)
+export interface BodyCodec {
+  encode(typeName: string, msg: any): Uint8Array;
+  decode(typeName: string, data: Uint8Array): any;
+}
+
+function utf8Encode(str: string): Uint8Array {
+  const out: number[] = [];
+  for (let i = 0; i < str.length; i++) {
+    let c = str.charCodeAt(i);
+    if (c >= 0xd800 && c < 0xdc00 && i + 1 < str.length) {
+      const next = str.charCodeAt(i + 1);
+      if (next >= 0xdc00 && next < 0xe000) {
+        c = 0x10000 + ((c - 0xd800) << 10) + (next - 0xdc00);
+        i++;
+      }
+    }
+    if (c < 0x80) {
+      out.push(c);
+    } else if (c < 0x800) {
+      out.push(0xc0 | (c >> 6), 0x80 | (c & 0x3f));
+    } else if (c < 0x10000) {
+      out.push(0xe0 | (c >> 12), 0x80 | ((c >> 6) & 0x3f), 0x80 | (c & 0x3f));
+    } else {
+      out.push(0xf0 | (c >> 18), 0x80 | ((c >> 12) & 0x3f), 0x80 | ((c >> 6) & 0x3f), 0x80 | (c & 0x3f));
+    }
+  }
+  return new Uint8Array(out);
+}
+
+function utf8Decode(bytes: Uint8Array): string {
+  let out = "";
+  for (let i = 0; i < bytes.length; ) {
+    const b = bytes[i++];
+    let c: number;
+    if (b < 0x80) {
+      c = b;
+    } else if (b < 0xe0) {
+      c = ((b & 0x1f) << 6) | (bytes[i++] & 0x3f);
+    } else if (b < 0xf0) {
+      c = ((b & 0x0f) << 12) | ((bytes[i++] & 0x3f) << 6) | (bytes[i++] & 0x3f);
+    } else {
+      c = ((b & 0x07) << 18) | ((bytes[i++] & 0x3f) << 12) | ((bytes[i++] & 0x3f) << 6) | (bytes[i++] & 0x3f);
+    }
+    if (c >= 0x10000) {
+      c -= 0x10000;
+      out += String.fromCharCode(0xd800 + (c >> 10), 0xdc00 + (c & 0x3ff));
+    } else {
+      out += String.fromCharCode(c);
+    }
+  }
+  return out;
+}
+
+// protobuf标准json格式(codec 1)
+export const JsonCodec: BodyCodec = {
+  encode(_typeName: string, msg: any): Uint8Array {
+    return utf8Encode(JSON.stringify(msg));
+  },
+  decode(_typeName: string, data: Uint8Array): any {
+    return data.length > 0 ? JSON.parse(utf8Decode(data)) : {};
+  },
+};
+
+export class GateError extends Error {
+  code: number;
+
+  constructor(code: number, message: string) {
+    super(message);
+    this.code = code;
+    Object.setPrototypeOf(this, GateError.prototype);
+  }
+}
+
+// WebSocket及小程序socket的最小接口
+export interface SocketLike {
+  binaryType?: string;
+  send(data: ArrayBuffer): void;
+  close(): void;
+  onopen: ((ev?: any) => void) | null;
+  onmessage: ((ev: { data: any }) => void) | null;
+  onclose: ((ev?: any) => void) | null;
+  onerror: ((ev?: any) => void) | null;
+}
+
+export interface ClientOptions {
+  // 请求使用的codec, 默认CodecJson
+  codec?: number;
+  // 编码格式 => 编解码器, 默认只有json; 使用protobuf(codec 0)时需提供, 例如基于protobufjs
+  codecs?: { [format: number]: BodyCodec };
+  // 调用超时(ms), 默认10000
+  timeout?: number;
+  // 解压带有压缩标记的消息体, 未提供时收到压缩的数据返回错误
+  decompress?: (flag: number, data: Uint8Array) => Uint8Array;
+  // 创建socket, 默认 new WebSocket(url)
+  socketFactory?: (url: string) => SocketLike;
+}
+
+interface PendingCall {
+  respType: string;
+  resolve: (msg: any) => void;
+  reject: (err: GateError) => void;
+  timer: any;
+}
+
+const pushIds: { [id: number]: boolean } = {
+{{- range .Pushes}}
+  {{.Id}}: true,
+{{- end}}
+};
+
+// 网关客户端: 按seq匹配响应, 推送协议(@push)交给onPush订阅的函数处理
+export class GateClient {
+  private url: string;
+  private options: ClientOptions;
+  private codecs: { [format: number]: BodyCodec };
+  private socket: SocketLike | null = null;
+  private seq = 0;
+  private pending: { [seq: number]: PendingCall } = {};
+  private pushHandlers: { [id: number]: Array<(msg: any) => void> } = {};
+
+  constructor(url: string, options: ClientOptions = {}) {
+    this.url = url;
+    this.options = options;
+    this.codecs = { [CodecJson]: JsonCodec, ...(options.codecs || {}) };
+  }
+
+  connect(): Promise<void> {
+    return new Promise<void>((resolve, reject) => {
+      const factory = this.options.socketFactory || ((url: string) => new WebSocket(url) as any as SocketLike);
+      const socket = factory(this.url);
+      let opened = false;
+      socket.binaryType = "arraybuffer";
+      socket.onopen = () => {
+        opened = true;
+        resolve();
+      };
+      socket.onerror = (ev?: any) => {
+        if (!opened) {
+          reject(ev);
+        }
+      };
+      socket.onclose = () => {
+        if (this.socket === socket) {
+          this.socket = null;
+        }
+        this.failAll(new GateError(ErrCode.Closed, "socket closed"));
+      };
+      socket.onmessage = (ev: { data: any }) => this.onData(ev.data);
+      this.socket = socket;
+    });
+  }
+
+  close(): void {
+    if (this.socket) {
+      this.socket.close();
+      this.socket = null;
+    }
+    this.failAll(new GateError(ErrCode.Closed, "socket closed"));
+  }
+
+  // 订阅推送, 返回取消订阅的函数
+  onPush(id: number, handler: (msg: any) => void): () => void {
+    const list = this.pushHandlers[id] || (this.pushHandlers[id] = []);
+    list.push(handler);
+    return () => {
+      const idx = list.indexOf(handler);
+      if (idx >= 0) {
+        list.splice(idx, 1);
+      }
+    };
+  }
+
+  // 发送@upid为upId的请求, 等待相同seq的响应
+  invoke(upId: number, reqType: string, respType: string, req: any): Promise<any> {
+    return new Promise<any>((resolve, reject) => {
+      const socket = this.socket;
+      if (!socket) {
+        reject(new GateError(ErrCode.Closed, "socket closed"));
+        return;
+      }
+      const codec = this.options.codec === undefined ? CodecJson : this.options.codec;
+      const bodyCodec = this.codecs[codec & CodecFormatMask];
+      if (!bodyCodec) {
+        reject(new GateError(ErrCode.Codec, "unknown codec " + codec));
+        return;
+      }
+      this.seq = (this.seq + 1) & 0xffff || 1;
+      const seq = this.seq;
+      if (this.pending[seq]) {
+        reject(new GateError(ErrCode.QueueFull, "too many pending requests"));
+        return;
+      }
+      let data: ArrayBuffer;
+      try {
+        data = encodePack({ seq: seq, id: upId, codec: codec & CodecFormatMask, body: bodyCodec.encode(reqType, req) });
+      } catch (e) {
+        reject(e instanceof GateError ? e : new GateError(ErrCode.Codec, String(e)));
+        return;
+      }
+      const timeout = this.options.timeout === undefined ? 10000 : this.options.timeout;
+      this.pending[seq] = {
+        respType: respType,
+        resolve: resolve,
+        reject: reject,
+        timer: setTimeout(() => {
+          delete this.pending[seq];
+          reject(new GateError(ErrCode.DeadlineExceeded, "timeout"));
+        }, timeout),
+      };
+      socket.send(data);
+    });
+  }
+{{range .Calls}}
+  // {{.FullMethod}} @upid {{.GetUpId}}{{if .GetDownId}} @downid {{.GetDownId}}{{end}}
+  {{.FuncName}}(req: {{.ReqType}}): Promise<{{.RespType}}> {
+    return this.invoke({{.GetUpId}}, "{{.ReqName}}", "{{.RespName}}", req);
+  }
+{{end}}
+  private onData(data: any): void {
+    for (const pack of decodePacks(data)) {
+      const call = pushIds[pack.id] ? undefined : this.pending[pack.seq];
+      if (call) {
+        delete this.pending[pack.seq];
+        clearTimeout(call.timer);
+        try {
+{{- with .ErrorReply}}
+          if (pack.id === {{.Id}}) {
+            const reply = this.decodeBody(pack, Id2Type[pack.id]);
+            call.reject(new GateError(Number(reply.{{.CodeField}} || 0), {{if .MsgField}}reply.{{.MsgField}} || ""{{else}}""{{end}}));
+            continue;
+          }
+{{- end}}
+          call.resolve(this.decodeBody(pack, call.respType));
+        } catch (e) {
+          call.reject(e instanceof GateError ? e : new GateError(ErrCode.Codec, String(e)));
+        }
+        continue;
+      }
+      const handlers = this.pushHandlers[pack.id];
+      const typeName = Id2Type[pack.id];
+      if (!handlers || handlers.length < 1 || !typeName) {
+        continue;
+      }
+      let msg: any;
+      try {
+        msg = this.decodeBody(pack, typeName);
+      } catch (e) {
+        continue;
+      }
+      for (const handler of handlers.slice()) {
+        handler(msg);
+      }
+    }
+  }
+
+  private decodeBody(pack: Pack, typeName: string): any {
+    let body = pack.body;
+    const flag = pack.codec & ~CodecFormatMask;
+    if (flag) {
+      if (!this.options.decompress) {
+        throw new GateError(ErrCode.Codec, "compressed body without decompress");
+      }
+      body = this.options.decompress(flag, body);
+    }
+    const bodyCodec = this.codecs[pack.codec & CodecFormatMask];
+    if (!bodyCodec) {
+      throw new GateError(ErrCode.Codec, "unknown codec " + pack.codec);
+    }
+    return bodyCodec.decode(typeName, body);
+  }
+
+  private failAll(err: GateError): void {
+    const pending = this.pending;
+    this.pending = {};
+    for (const seq of Object.keys(pending)) {
+      const call = pending[Number(seq)];
+      clearTimeout(call.timer);
+      call.reject(err);
+    }
+  }
+}
+`))
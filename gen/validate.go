@@ -0,0 +1,304 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: validate.go
+ * @time: 2026/10/18 11:05
+ */
+package gen
+
+import (
+	`errors`
+	`fmt`
+	`strconv`
+	`strings`
+
+	`github.com/golang/protobuf/protoc-gen-go/generator`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+const maxRouteId = 65535 // 包头Id为uint16
+
+// 待生成文件中的方法及其源码位置
+type methodInfo struct {
+	*methodWithComment
+	File *descriptor.File
+	Pos  string // file:line
+	Name string // Service/Method
+}
+
+// 对应生成代码中id2meth的 package.TargetService/Method
+func (p *methodInfo) FullMethod() string {
+	return p.File.GoPkg.Name + "." + generator.CamelCase(p.GetTargetSvrName()) + "/" + generator.CamelCase(p.GetName())
+}
+
+// 按文件/服务/方法的顺序收集所有方法
+func (p *TcpGenerator) loadMethods(targets []*descriptor.File) []*methodInfo {
+	var list []*methodInfo
+	for _, file := range targets {
+		comments := p.reg.fileComments[file.GetName()]
+		for si, svc := range file.Services {
+			svcRoute := getServiceRoute(svc)
+			svcIt := &serviceWithComment{Service: svc, Comment: comments[fmt.Sprintf("%d,%d", servicePath, si)]}
+			svcIt.ParseComment()
+			for mi, m := range svc.Methods {
+				path := fmt.Sprintf("%d,%d,%d,%d", servicePath, si, methodPath, mi)
+				mIt := &methodWithComment{
+					Method:         m,
+					Comment:        comments[path],
+					Route:          getMethodRoute(m),
+					SvcRoute:       svcRoute,
+					SvcCommentList: svcIt.CommentList,
+					DefTimeout:     p.defaultTimeout,
+					DefAuth:        p.defaultAuth,
+				}
+				mIt.ParseComment()
+				list = append(list, &methodInfo{
+					methodWithComment: mIt,
+					File:              file,
+					Pos:               p.reg.SourcePos(file.GetName(), path),
+					Name:              svc.GetName() + "/" + m.GetName(),
+				})
+			}
+		}
+	}
+	return list
+}
+
+// id的占用者, 用于报告冲突位置
+type idOwner struct {
+	pos  string // file:line
+	name string // Service/Method
+	msg  string // 绑定的协议名
+}
+
+// 查找第一行包含tag的注释, 返回tag后的第一个值
+func findTagValue(lines []string, tag string) (value, line string, found bool) {
+	for _, it := range lines {
+		if strings.Contains(it, tag) {
+			value = strings.TrimSpace(strings.TrimPrefix(it, tag))
+			value = strings.TrimSpace(strings.Split(value, " ")[0])
+			return value, it, true
+		}
+	}
+	return "", "", false
+}
+
+// 校验注释中的id标签, 与GetUpId/GetDownId的解析方式一致
+func checkTagId(lines []string, tag string) (bool, error) {
+	value, line, found := findTagValue(lines, tag)
+	if !found {
+		return false, nil
+	}
+	if !strings.HasPrefix(line, tag) {
+		return true, fmt.Errorf("bad tag syntax %q, %s must start the line", line, tag)
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return true, fmt.Errorf("bad %s value %q", tag, value)
+	}
+	if v < 1 || v > maxRouteId {
+		return true, fmt.Errorf("%s %d out of range [1, %d]", tag, v, maxRouteId)
+	}
+	return true, nil
+}
+
+// 检查方法的路由标签/选项, 返回所有错误
+func (p *methodWithComment) checkTags() []error {
+	if p.CommentList == nil {
+		p.ParseComment()
+	}
+	var errs []error
+	for _, line := range p.CommentList {
+		if strings.HasPrefix(line, TagTransmit) && line != TagTransmit {
+			errs = append(errs, fmt.Errorf("bad tag syntax %q, %s must be on its own line", line, TagTransmit))
+		}
+	}
+	if !p.CanOutput() {
+		return errs
+	}
+
+	for _, tag := range []string{TagTarget, TagTarPkg} {
+		if value, line, found := findTagValue(p.CommentList, tag); found && (len(value) < 1 || !strings.HasPrefix(line, tag)) {
+			errs = append(errs, fmt.Errorf("bad tag syntax %q, want '%s <name>'", line, tag))
+		}
+	}
+
+	if p.Route != nil && p.Route.UpId > 0 {
+		if p.Route.UpId > maxRouteId {
+			errs = append(errs, fmt.Errorf("tcpgw.route up_id %d out of range [1, %d]", p.Route.UpId, maxRouteId))
+		}
+	} else {
+		// @upid优先, 兼容旧的@id
+		upTag := TagUpId
+		if _, _, found := findTagValue(p.CommentList, TagUpId); !found {
+			upTag = TagId
+		}
+		found, err := checkTagId(p.CommentList, upTag)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !found {
+			errs = append(errs, fmt.Errorf("missing %s", TagUpId))
+		}
+	}
+
+	if p.Route != nil && p.Route.DownId > 0 {
+		if p.Route.DownId > maxRouteId {
+			errs = append(errs, fmt.Errorf("tcpgw.route down_id %d out of range [1, %d]", p.Route.DownId, maxRouteId))
+		}
+	} else if _, err := checkTagId(p.CommentList, TagDownId); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := parseTimeoutTag(p.CommentList); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseCompressTag(p.CommentList); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseRateLimitTag(p.CommentList); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseAuthTag(p.CommentList); err != nil {
+		errs = append(errs, err)
+	}
+	if p.Route != nil {
+		if _, err := parseTimeout(p.Route.Timeout); err != nil {
+			errs = append(errs, fmt.Errorf("tcpgw.route: %v", err))
+		}
+		if _, err := parseCompress(p.Route.Compress); err != nil {
+			errs = append(errs, fmt.Errorf("tcpgw.route: %v", err))
+		}
+		if _, err := parseRateLimit(p.Route.Ratelimit); err != nil {
+			errs = append(errs, fmt.Errorf("tcpgw.route: %v", err))
+		}
+		if _, err := parseAuth(p.Route.Auth); err != nil {
+			errs = append(errs, fmt.Errorf("tcpgw.route: %v", err))
+		}
+	}
+	return errs
+}
+
+// 校验所有待生成文件的路由id, 错误信息带有proto文件及行号
+func (p *TcpGenerator) validate(targets []*descriptor.File) error {
+	var errs []string
+	report := func(pos, format string, args ...interface{}) {
+		errs = append(errs, pos+": "+fmt.Sprintf(format, args...))
+	}
+
+	upIds := map[uint16]*idOwner{}    // id2meth
+	id2msg := map[uint16]*idOwner{}   // id2struct
+	msg2id := map[string]uint16{}     // structName2id, 仅响应协议
+	msgOwner := map[string]*idOwner{} // structName2id的首个占用者
+
+	// unique: 协议只能对应一个id, 非共用的响应协议需要通过GetIdByMsgObj找到@downid
+	bind := func(id uint16, msg *descriptor.Message, owner *idOwner, tag string, unique bool) {
+		if id == 0 {
+			return
+		}
+		fqmn := msg.FQMN()
+		if prev, ok := id2msg[id]; ok && prev.msg != fqmn {
+			report(owner.pos, "%s: %s %d already bound to %s by %s (%s)", owner.name, tag, id, prev.msg, prev.name, prev.pos)
+			return
+		}
+		id2msg[id] = &idOwner{pos: owner.pos, name: owner.name, msg: fqmn}
+		if !unique {
+			return
+		}
+
+		name := msg.GetName()
+		if prevId, ok := msg2id[name]; ok && prevId != id {
+			prev := msgOwner[name]
+			report(owner.pos, "%s: message %s bound to %s %d, but already bound to id %d by %s (%s)", owner.name, name, tag, id, prevId, prev.name, prev.pos)
+			return
+		}
+		msg2id[name] = id
+		msgOwner[name] = owner
+	}
+
+	if _, err := parseAuth(p.defaultAuth); err != nil {
+		errs = append(errs, "parameter auth: "+err.Error())
+	}
+	// 服务级配置
+	for _, file := range targets {
+		comments := p.reg.fileComments[file.GetName()]
+		for si, svc := range file.Services {
+			path := fmt.Sprintf("%d,%d", servicePath, si)
+			pos := p.reg.SourcePos(file.GetName(), path)
+			svcIt := &serviceWithComment{Service: svc, Comment: comments[path]}
+			svcIt.ParseComment()
+			if _, err := parseTimeoutTag(svcIt.CommentList); err != nil {
+				report(pos, "%s: %v", svc.GetName(), err)
+			}
+			if _, err := parseAuthTag(svcIt.CommentList); err != nil {
+				report(pos, "%s: %v", svc.GetName(), err)
+			}
+			if svcRoute := getServiceRoute(svc); svcRoute != nil {
+				if _, err := parseTimeout(svcRoute.Timeout); err != nil {
+					report(pos, "%s: tcpgw.service_route: %v", svc.GetName(), err)
+				}
+				if _, err := parseAuth(svcRoute.Auth); err != nil {
+					report(pos, "%s: tcpgw.service_route: %v", svc.GetName(), err)
+				}
+			}
+		}
+	}
+
+	for _, file := range targets {
+		declares, err := p.loadDeclares(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, d := range declares {
+			bind(d.Id, d.Message, &idOwner{pos: d.Pos, name: TagDeclare + " " + d.GetName()}, TagDeclare, true)
+		}
+		pushes, err := p.loadPushes(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, d := range pushes {
+			bind(d.Id, d.Message, &idOwner{pos: d.Pos, name: TagPush + " " + d.GetName()}, TagPush, true)
+		}
+	}
+
+	methods := p.loadMethods(targets)
+	// 被多个方法共用的响应协议允许对应不同的@downid
+	respCount := map[string]int{}
+	for _, m := range methods {
+		if m.CanOutput() && m.GetDownId() != 0 {
+			respCount[m.ResponseType.GetName()]++
+		}
+	}
+
+	for _, m := range methods {
+		owner := &idOwner{pos: m.Pos, name: m.Name}
+		if tagErrs := m.checkTags(); len(tagErrs) > 0 {
+			for _, err := range tagErrs {
+				report(owner.pos, "%s: %v", owner.name, err)
+			}
+			continue
+		}
+		if !m.CanOutput() {
+			continue
+		}
+
+		upId := m.GetUpId()
+		if prev, ok := upIds[upId]; ok {
+			report(owner.pos, "%s: duplicate %s %d, already used by %s (%s)", owner.name, TagUpId, upId, prev.name, prev.pos)
+			continue
+		}
+		upIds[upId] = owner
+		bind(upId, m.RequestType, owner, TagUpId, false)
+		bind(m.GetDownId(), m.ResponseType, owner, TagDownId, respCount[m.ResponseType.GetName()] < 2)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
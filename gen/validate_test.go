@@ -0,0 +1,168 @@
+/**
+ * @version: 1.0.0
+ * @author: zhangguodong:general_zgd
+ * @license: LGPL v3
+ * @contact: general_zgd@163.com
+ * @site: github.com/generalzgd
+ * @software: GoLand
+ * @file: validate_test.go
+ * @time: 2026/10/19 10:45
+ */
+package gen
+
+import (
+	`fmt`
+	`strings`
+	`testing`
+
+	`github.com/golang/protobuf/proto`
+	descriptor2 `github.com/golang/protobuf/protoc-gen-go/descriptor`
+	plugin_go `github.com/golang/protobuf/protoc-gen-go/plugin`
+	`github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor`
+)
+
+type testMethod struct {
+	name    string
+	in, out string
+	comment string
+}
+
+// 构造只有一个服务(Gate)的 im.proto, 方法i的注释位于第 10+i 行
+func newTestGenerator(t *testing.T, methods []testMethod) (*TcpGenerator, []*descriptor.File) {
+	file := &descriptor2.FileDescriptorProto{
+		Name:    proto.String("im.proto"),
+		Package: proto.String("im"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptor2.FileOptions{GoPackage: proto.String("im")},
+	}
+	for _, name := range []string{"Req", "Reply", "Req2", "Reply2"} {
+		file.MessageType = append(file.MessageType, &descriptor2.DescriptorProto{Name: proto.String(name)})
+	}
+	svc := &descriptor2.ServiceDescriptorProto{Name: proto.String("Gate")}
+	comments := map[string]string{}
+	lines := map[string]int{}
+	for i, m := range methods {
+		svc.Method = append(svc.Method, &descriptor2.MethodDescriptorProto{
+			Name:       proto.String(m.name),
+			InputType:  proto.String(".im." + m.in),
+			OutputType: proto.String(".im." + m.out),
+		})
+		path := fmt.Sprintf("%d,0,%d,%d", servicePath, methodPath, i)
+		comments[path] = m.comment
+		lines[path] = 10 + i
+	}
+	file.Service = append(file.Service, svc)
+
+	reg := NewRegistry()
+	if err := reg.Load(&plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"im.proto"},
+		ProtoFile:      []*descriptor2.FileDescriptorProto{file},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reg.AddComments("im.proto", comments)
+	reg.AddLines("im.proto", lines)
+	target, err := reg.LookupFile("im.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(reg, "Handler", "", ""), []*descriptor.File{target}
+}
+
+func TestValidate(t *testing.T) {
+	tags := func(upId, downId int) string {
+		return fmt.Sprintf("// @transmit\n// @target Im\n// @upid %d\n// @downid %d", upId, downId)
+	}
+	tests := []struct {
+		name    string
+		methods []testMethod
+		wantErr string // 为空表示通过
+	}{
+		{
+			name: "ok",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2)},
+				{"B", "Req2", "Reply2", tags(3, 4)},
+			},
+		},
+		{
+			name: "shared request",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2)},
+				{"B", "Req", "Reply2", tags(3, 4)},
+			},
+		},
+		{
+			name: "not transmitted",
+			methods: []testMethod{
+				{"A", "Req", "Reply", "// 不转发"},
+			},
+		},
+		{
+			name: "duplicate upid",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2)},
+				{"B", "Req2", "Reply2", tags(1, 4)},
+			},
+			wantErr: "im.proto:11: Gate/B: duplicate @upid 1, already used by Gate/A (im.proto:10)",
+		},
+		{
+			name: "id bound to other message",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2)},
+				{"B", "Req2", "Reply2", tags(3, 1)},
+			},
+			wantErr: "im.proto:11: Gate/B: @downid 1 already bound to .im.Req by Gate/A (im.proto:10)",
+		},
+		{
+			name: "shared response",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2)},
+				{"B", "Req2", "Reply", tags(3, 4)},
+			},
+		},
+		{
+			name: "missing upid",
+			methods: []testMethod{
+				{"A", "Req", "Reply", "// @transmit\n// @target Im"},
+			},
+			wantErr: "Gate/A: missing @upid",
+		},
+		{
+			name: "upid out of range",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(70000, 2)},
+			},
+			wantErr: "Gate/A: @upid 70000 out of range [1, 65535]",
+		},
+		{
+			name: "bad transmit syntax",
+			methods: []testMethod{
+				{"A", "Req", "Reply", "// @transmit yes\n// @transmit\n// @upid 1"},
+			},
+			wantErr: `bad tag syntax "@transmit yes"`,
+		},
+		{
+			name: "bad timeout",
+			methods: []testMethod{
+				{"A", "Req", "Reply", tags(1, 2) + "\n// @timeout soon"},
+			},
+			wantErr: `Gate/A: bad timeout value "soon"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, targets := newTestGenerator(t, tt.methods)
+			err := g.validate(targets)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
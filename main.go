@@ -20,6 +20,7 @@ import (
 	`runtime`
 	"strconv"
 	`strings`
+	`time`
 
 	`github.com/golang/glog`
 	`github.com/golang/protobuf/proto`
@@ -39,8 +40,38 @@ var (
 	versionFlag        = flag.Bool("version", false, "print current version")
 	debug              = flag.Bool("debug", false, "")
 	definePrefix       = flag.String("define_prefix", "", "var define prefix")
+	lockFile           = flag.String("lock_file", "", "id lock file, keeps @upid/@downid stable across regenerations")
+	declares           stringList
+	errorReply         = flag.String("error_reply", "", "error reply message(package.Message) built from transmit errors, must be declared with an id")
+	jsonOrigName       = flag.Bool("json_orig_name", false, "json codec uses proto field names instead of lowerCamelCase")
+	jsonEmitDefaults   = flag.Bool("json_emit_defaults", false, "json codec renders fields with zero values")
+	jsonEnumsAsInts    = flag.Bool("json_enums_as_ints", false, "json codec renders enums as integers")
+	auth               = flag.String("auth", "none", "default auth policy of methods without @auth: none, required or role:<name>")
+	goClient           = flag.Bool("go_client", false, "also generate a go tcp client of the gateway")
+	tsClient           = flag.Bool("ts_client", false, "also generate a typescript websocket client module(.pb.tcpgw.ts)")
+	csClient           = flag.Bool("cs_client", false, "also generate c# command ids, message parsers and pack head(.pb.tcpgw.cs)")
+	genManifest        = flag.Bool("manifest", false, "also generate a json route manifest(.pb.tcpgw.json)")
+	genDoc             = flag.Bool("doc", false, "also generate protocol docs in markdown(.pb.tcpgw.md) and html(.pb.tcpgw.html)")
+	genAsyncApi        = flag.Bool("asyncapi", false, "also generate an asyncapi 2.x document(.pb.tcpgw.asyncapi.json)")
+	timeout            = flag.Duration("timeout", 5*time.Second, "default call timeout of non-streaming methods, overridden by @timeout")
 )
 
+// 可重复的参数, 例: declare=comm.Error=8197,declare=im.Kick=5001
+type stringList []string
+
+func (p *stringList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *stringList) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func init() {
+	flag.Var(&declares, "declare", "declare error/notify message with id, package.Message=id, repeatable")
+}
+
 var (
 	version = "1.0.1"
 )
@@ -96,7 +127,14 @@ func main() {
 		for _, p := range list {
 			spec := strings.SplitN(p, "=", 2)
 			if len(spec) == 1 {
-				if err := flag.CommandLine.Set(spec[0], ""); err != nil {
+				// 布尔参数可省略值, 例: json_orig_name
+				value := ""
+				if f := flag.CommandLine.Lookup(spec[0]); f != nil {
+					if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+						value = "true"
+					}
+				}
+				if err := flag.CommandLine.Set(spec[0], value); err != nil {
 					log.Fatal("cannot set flag", p)
 				}
 				continue
@@ -109,6 +147,20 @@ func main() {
 	}
 
 	g := gen.New(reg, *registerFuncSuffix, *pathType, *definePrefix)
+	g.SetLockFile(*lockFile)
+	g.SetDefaultTimeout(*timeout)
+	g.SetDefaultAuth(*auth)
+	g.SetGoClient(*goClient)
+	g.SetTsClient(*tsClient)
+	g.SetCsClient(*csClient)
+	g.SetManifest(*genManifest)
+	g.SetDoc(*genDoc)
+	g.SetAsyncApi(*genAsyncApi)
+	g.SetErrorReply(*errorReply)
+	g.SetJsonOptions(*jsonOrigName, *jsonEmitDefaults, *jsonEnumsAsInts)
+	for _, spec := range declares {
+		g.AddDeclare(spec)
+	}
 
 	reg.SetPrefix(*importPrefix)
 	reg.SetImportPath(*importPath)
@@ -117,6 +169,18 @@ func main() {
 		emitError(err)
 	}
 
+	// 所有文件的注释, 依赖文件中的协议可能带有@push
+	for _, pf := range req.ProtoFile {
+		f, err := reg.LookupFile(pf.GetName())
+		if err != nil {
+			log.Fatal(err)
+		}
+		comments, lines := extractComments(f)
+		reg.AddComments(*f.Name, comments)
+		reg.AddLines(*f.Name, lines)
+		// log.Println(comments)
+	}
+
 	var targets []*descriptor.File
 	for _, target := range req.FileToGenerate {
 		f, err := reg.LookupFile(target)
@@ -124,10 +188,6 @@ func main() {
 			log.Fatal(err)
 		}
 		targets = append(targets, f)
-		//
-		comments := extractComments(f)
-		reg.AddComments(*f.Name, comments)
-		// log.Println(comments)
 	}
 
 	if err := reg.ParseCommentsSource(req.ProtoFile); err != nil {
@@ -143,19 +203,27 @@ func main() {
 	emitFiles(out)
 }
 
-func extractComments(file *descriptor.File) map[string]string {
+// 返回 path => 注释, path => 起始行号
+func extractComments(file *descriptor.File) (map[string]string, map[string]int) {
 	comments := make(map[string]string)
+	lines := make(map[string]int)
 	for _, loc := range file.GetSourceCodeInfo().GetLocation() {
-		if loc.LeadingComments == nil {
-			continue
-		}
 		var t []string
 		for _, n := range loc.Path {
 			t = append(t, strconv.Itoa(int(n)))
 		}
-		comments[strings.Join(t, ",")] = strings.TrimSpace(*loc.LeadingComments)
+		key := strings.Join(t, ",")
+		if len(loc.Span) > 0 {
+			if _, ok := lines[key]; !ok {
+				lines[key] = int(loc.Span[0]) + 1
+			}
+		}
+		if loc.LeadingComments == nil {
+			continue
+		}
+		comments[key] = strings.TrimSpace(*loc.LeadingComments)
 	}
-	return comments
+	return comments, lines
 }
 
 func emitFiles(out []*plugin_go.CodeGeneratorResponse_File) {
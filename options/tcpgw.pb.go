@@ -0,0 +1,248 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: tcpgw.proto
+
+package options
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// 方法路由, 设置即表示需要转发(等同 @transmit)
+type Route struct {
+	// 请求协议对应id, 等同 @upid
+	UpId uint32 `protobuf:"varint,1,opt,name=up_id,json=upId,proto3" json:"up_id,omitempty"`
+	// 响应协议对应id, 等同 @downid
+	DownId uint32 `protobuf:"varint,2,opt,name=down_id,json=downId,proto3" json:"down_id,omitempty"`
+	// 目标后端服务名, 等同 @target
+	Target string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	// 目标服务所在的包, 等同 @tarpkg
+	TarPkg string `protobuf:"bytes,4,opt,name=tar_pkg,json=tarPkg,proto3" json:"tar_pkg,omitempty"`
+	// 调用超时, 例如 "800ms", 等同 @timeout
+	Timeout string `protobuf:"bytes,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// 响应压缩阈值及算法, 例如 "4k gzip", 等同 @compress
+	Compress string `protobuf:"bytes,6,opt,name=compress,proto3" json:"compress,omitempty"`
+	// 限流, 例如 "10/s burst=20 by=uid", 等同 @ratelimit
+	Ratelimit string `protobuf:"bytes,7,opt,name=ratelimit,proto3" json:"ratelimit,omitempty"`
+	// 鉴权要求 none|required|role:<name>, 等同 @auth
+	Auth                 string   `protobuf:"bytes,8,opt,name=auth,proto3" json:"auth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8727a3958ac0129c, []int{0}
+}
+
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Route.Unmarshal(m, b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+}
+func (m *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(m, src)
+}
+func (m *Route) XXX_Size() int {
+	return xxx_messageInfo_Route.Size(m)
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetUpId() uint32 {
+	if m != nil {
+		return m.UpId
+	}
+	return 0
+}
+
+func (m *Route) GetDownId() uint32 {
+	if m != nil {
+		return m.DownId
+	}
+	return 0
+}
+
+func (m *Route) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *Route) GetTarPkg() string {
+	if m != nil {
+		return m.TarPkg
+	}
+	return ""
+}
+
+func (m *Route) GetTimeout() string {
+	if m != nil {
+		return m.Timeout
+	}
+	return ""
+}
+
+func (m *Route) GetCompress() string {
+	if m != nil {
+		return m.Compress
+	}
+	return ""
+}
+
+func (m *Route) GetRatelimit() string {
+	if m != nil {
+		return m.Ratelimit
+	}
+	return ""
+}
+
+func (m *Route) GetAuth() string {
+	if m != nil {
+		return m.Auth
+	}
+	return ""
+}
+
+// 服务级路由, 作为该服务下方法的默认值
+type ServiceRoute struct {
+	// 默认目标后端服务名
+	Target string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	// 默认目标服务所在的包
+	TarPkg string `protobuf:"bytes,2,opt,name=tar_pkg,json=tarPkg,proto3" json:"tar_pkg,omitempty"`
+	// 默认调用超时(非流式方法)
+	Timeout string `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// 默认鉴权要求
+	Auth                 string   `protobuf:"bytes,4,opt,name=auth,proto3" json:"auth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServiceRoute) Reset()         { *m = ServiceRoute{} }
+func (m *ServiceRoute) String() string { return proto.CompactTextString(m) }
+func (*ServiceRoute) ProtoMessage()    {}
+func (*ServiceRoute) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8727a3958ac0129c, []int{1}
+}
+
+func (m *ServiceRoute) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceRoute.Unmarshal(m, b)
+}
+func (m *ServiceRoute) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceRoute.Marshal(b, m, deterministic)
+}
+func (m *ServiceRoute) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceRoute.Merge(m, src)
+}
+func (m *ServiceRoute) XXX_Size() int {
+	return xxx_messageInfo_ServiceRoute.Size(m)
+}
+func (m *ServiceRoute) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceRoute.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceRoute proto.InternalMessageInfo
+
+func (m *ServiceRoute) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *ServiceRoute) GetTarPkg() string {
+	if m != nil {
+		return m.TarPkg
+	}
+	return ""
+}
+
+func (m *ServiceRoute) GetTimeout() string {
+	if m != nil {
+		return m.Timeout
+	}
+	return ""
+}
+
+func (m *ServiceRoute) GetAuth() string {
+	if m != nil {
+		return m.Auth
+	}
+	return ""
+}
+
+var E_Route = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*Route)(nil),
+	Field:         51001,
+	Name:          "tcpgw.route",
+	Tag:           "bytes,51001,opt,name=route",
+	Filename:      "tcpgw.proto",
+}
+
+var E_ServiceRoute = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: (*ServiceRoute)(nil),
+	Field:         51001,
+	Name:          "tcpgw.service_route",
+	Tag:           "bytes,51001,opt,name=service_route",
+	Filename:      "tcpgw.proto",
+}
+
+func init() {
+	proto.RegisterType((*Route)(nil), "tcpgw.Route")
+	proto.RegisterType((*ServiceRoute)(nil), "tcpgw.ServiceRoute")
+	proto.RegisterExtension(E_Route)
+	proto.RegisterExtension(E_ServiceRoute)
+}
+
+func init() { proto.RegisterFile("tcpgw.proto", fileDescriptor_8727a3958ac0129c) }
+
+var fileDescriptor_8727a3958ac0129c = []byte{
+	// 353 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xbd, 0x6e, 0xea, 0x30,
+	0x18, 0x55, 0x20, 0x09, 0x60, 0x60, 0x31, 0xd2, 0xbd, 0x16, 0xba, 0xba, 0x45, 0x4c, 0x2c, 0x24,
+	0x52, 0xbb, 0x81, 0xba, 0x54, 0x5d, 0x18, 0xaa, 0x56, 0xe9, 0xd6, 0x0e, 0x28, 0xc4, 0xae, 0xb1,
+	0x20, 0xb1, 0xe5, 0x7c, 0x2e, 0x52, 0x1f, 0xa2, 0xef, 0xd3, 0x97, 0xe8, 0x33, 0x55, 0xb1, 0xc3,
+	0x4f, 0x5b, 0x31, 0x25, 0xe7, 0x1c, 0xfb, 0x9c, 0xf3, 0x7d, 0x09, 0xea, 0x42, 0xa6, 0xf8, 0x2e,
+	0x52, 0x5a, 0x82, 0xc4, 0x81, 0x05, 0xc3, 0x11, 0x97, 0x92, 0x6f, 0x59, 0x6c, 0xc9, 0x95, 0x79,
+	0x89, 0x29, 0x2b, 0x33, 0x2d, 0x14, 0x48, 0xed, 0x0e, 0x8e, 0x3f, 0x3d, 0x14, 0x24, 0xd2, 0x00,
+	0xc3, 0x03, 0x14, 0x18, 0xb5, 0x14, 0x94, 0x78, 0x23, 0x6f, 0xd2, 0x4f, 0x7c, 0xa3, 0x16, 0x14,
+	0xff, 0x45, 0x2d, 0x2a, 0x77, 0x45, 0x45, 0x37, 0x2c, 0x1d, 0x56, 0x70, 0x41, 0xf1, 0x1f, 0x14,
+	0x42, 0xaa, 0x39, 0x03, 0xd2, 0x1c, 0x79, 0x93, 0x4e, 0x52, 0xa3, 0xea, 0x02, 0xa4, 0x7a, 0xa9,
+	0x36, 0x9c, 0xf8, 0x07, 0xe1, 0x61, 0xc3, 0x31, 0x41, 0x2d, 0x10, 0x39, 0x93, 0x06, 0x48, 0x60,
+	0x85, 0x3d, 0xc4, 0x43, 0xd4, 0xce, 0x64, 0xae, 0x34, 0x2b, 0x4b, 0x12, 0x5a, 0xe9, 0x80, 0xf1,
+	0x3f, 0xd4, 0xd1, 0x29, 0xb0, 0xad, 0xc8, 0x05, 0x90, 0x96, 0x15, 0x8f, 0x04, 0xc6, 0xc8, 0x4f,
+	0x0d, 0xac, 0x49, 0xdb, 0x0a, 0xf6, 0x7d, 0x9c, 0xa3, 0xde, 0x23, 0xd3, 0xaf, 0x22, 0x63, 0x6e,
+	0xac, 0x63, 0x51, 0xef, 0x5c, 0xd1, 0xc6, 0xb9, 0xa2, 0xcd, 0xef, 0x45, 0xf7, 0x71, 0xfe, 0x31,
+	0x6e, 0x76, 0x8b, 0x02, 0x6d, 0x73, 0xfe, 0x47, 0x6e, 0xd7, 0xd1, 0x7e, 0xd7, 0xd1, 0x1d, 0x83,
+	0xb5, 0xa4, 0xf7, 0x0a, 0x84, 0x2c, 0x4a, 0xf2, 0xf1, 0x5e, 0xb9, 0x75, 0x2f, 0x7b, 0x91, 0xfb,
+	0x4e, 0xb6, 0x5d, 0xe2, 0x2e, 0xcf, 0x9e, 0x51, 0xbf, 0x74, 0xa5, 0x97, 0xce, 0xed, 0xe2, 0x97,
+	0x5b, 0x3d, 0xd4, 0x4f, 0xbb, 0x41, 0x6d, 0x77, 0x3a, 0x73, 0xd2, 0x2b, 0x4f, 0xd0, 0xcd, 0xf5,
+	0xd3, 0x9c, 0x0b, 0x58, 0x9b, 0x55, 0x94, 0xc9, 0x3c, 0xe6, 0xac, 0x60, 0x3a, 0xdd, 0xbe, 0x71,
+	0xea, 0xfe, 0x8a, 0x6c, 0xca, 0x59, 0x31, 0xe5, 0x5a, 0x65, 0x53, 0xeb, 0x14, 0x4b, 0x97, 0x30,
+	0xaf, 0x9f, 0xab, 0xd0, 0x1e, 0xbb, 0xfa, 0x1a, 0x00, 0xf1, 0x0f, 0xea, 0xfb, 0x60, 0x02, 0x00,
+	0x00,
+}